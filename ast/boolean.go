@@ -8,6 +8,7 @@ type Boolean struct {
 }
 
 func (b *Boolean) expressionNode() {}
+func (b *Boolean) patternNode()    {}
 func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
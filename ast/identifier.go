@@ -15,6 +15,9 @@ func (i *Identifier) TokenLiteral() string {
 // say we have let x = 5; let y = x; the identifier x here does produce a value
 func (i *Identifier) expressionNode() {}
 
+// the simplest pattern, binds the whole value
+func (i *Identifier) patternNode() {}
+
 func (i *Identifier) String() string {
 	return i.Value
-}
\ No newline at end of file
+}
@@ -9,6 +9,9 @@ type IntegerLiteral struct {
 
 func (i *IntegerLiteral) expressionNode() {}
 
+// a literal in a match arm matches values equal to it
+func (i *IntegerLiteral) patternNode() {}
+
 func (i *IntegerLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
@@ -0,0 +1,500 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ekediala/interpreter/token"
+)
+
+// jsonNode is the shape every node takes when encoded. The layout is stable so tools outside Go can rely on it:
+// kind is the name of the node type, position and token come from the node's token, value holds the value of literals and identifiers, children holds named child nodes.
+// Nodes with a list of children use statements (root node), elements (array pattern, struct statement), pairs (hash pattern, struct literal) or arms (match expression)
+type jsonNode struct {
+	Kind       string               `json:"kind"`
+	Position   *token.Position      `json:"position,omitempty"`
+	Token      *jsonToken           `json:"token,omitempty"`
+	Operator   string               `json:"operator,omitempty"`
+	Value      json.RawMessage      `json:"value,omitempty"`
+	Children   map[string]*jsonNode `json:"children,omitempty"`
+	Statements []*jsonNode          `json:"statements,omitempty"`
+	Elements   []*jsonNode          `json:"elements,omitempty"`
+	Pairs      []jsonPair           `json:"pairs,omitempty"`
+	Arms       []jsonArm            `json:"arms,omitempty"`
+}
+
+type jsonPair struct {
+	Key   *jsonNode `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+type jsonArm struct {
+	Pattern *jsonNode `json:"pattern"`
+	Guard   *jsonNode `json:"guard,omitempty"`
+	Body    *jsonNode `json:"body"`
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+}
+
+// EncodeJSON encodes node and all of its children
+func EncodeJSON(node Node) ([]byte, error) {
+	n, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+// DecodeJSON reconstructs the node encoded by EncodeJSON
+func DecodeJSON(data []byte) (Node, error) {
+	var n jsonNode
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return decodeNode(&n)
+}
+
+func (r *RootNode) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(r)
+}
+
+func (r *RootNode) UnmarshalJSON(data []byte) error {
+	node, err := DecodeJSON(data)
+	if err != nil {
+		return err
+	}
+
+	root, ok := node.(*RootNode)
+	if !ok {
+		return fmt.Errorf("expected %s, got %s", "RootNode", kindOf(node))
+	}
+
+	*r = *root
+	return nil
+}
+
+func encodeNode(node Node) (*jsonNode, error) {
+	n := jsonNode{Kind: kindOf(node)}
+
+	var err error
+	switch node := node.(type) {
+	case *RootNode:
+		n.Statements = make([]*jsonNode, 0, len(node.Statements))
+		for _, stmt := range node.Statements {
+			child, err := encodeNode(stmt)
+			if err != nil {
+				return nil, err
+			}
+			n.Statements = append(n.Statements, child)
+		}
+		return &n, nil
+
+	case *LetStatement:
+		n.setToken(node.Token)
+		err = n.addChildren(map[string]Node{"name": node.Name, "value": node.Value})
+
+	case *ReturnStatement:
+		n.setToken(node.Token)
+		err = n.addChildren(map[string]Node{"returnValue": node.ReturnValue})
+
+	case *ExpressionStatement:
+		n.setToken(node.Token)
+		err = n.addChildren(map[string]Node{"expression": node.Expression})
+
+	case *PrefixExpression:
+		n.setToken(node.Token)
+		n.Operator = node.Operator
+		err = n.addChildren(map[string]Node{"right": node.Right})
+
+	case *InfixExpression:
+		n.setToken(node.Token)
+		n.Operator = node.Operator
+		err = n.addChildren(map[string]Node{"left": node.Left, "right": node.Right})
+
+	case *MatchExpression:
+		n.setToken(node.Token)
+		err = n.addChildren(map[string]Node{"value": node.Value})
+		n.Arms = make([]jsonArm, 0, len(node.Arms))
+		for _, arm := range node.Arms {
+			encoded, err := encodeArm(arm)
+			if err != nil {
+				return nil, err
+			}
+			n.Arms = append(n.Arms, encoded)
+		}
+
+	case *StructStatement:
+		n.setToken(node.Token)
+		err = n.addChildren(map[string]Node{"name": node.Name})
+		n.Elements = make([]*jsonNode, 0, len(node.Fields))
+		for _, field := range node.Fields {
+			child, err := encodeNode(field)
+			if err != nil {
+				return nil, err
+			}
+			n.Elements = append(n.Elements, child)
+		}
+
+	case *StructLiteral:
+		n.setToken(node.Token)
+		err = n.addChildren(map[string]Node{"name": node.Name})
+		n.Pairs = make([]jsonPair, 0, len(node.Fields))
+		for _, field := range node.Fields {
+			key, err := encodeNode(field.Name)
+			if err != nil {
+				return nil, err
+			}
+			value, err := encodeNode(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			n.Pairs = append(n.Pairs, jsonPair{Key: key, Value: value})
+		}
+
+	case *ArrayPattern:
+		n.setToken(node.Token)
+		n.Elements = make([]*jsonNode, 0, len(node.Elements))
+		for _, el := range node.Elements {
+			child, err := encodeNode(el)
+			if err != nil {
+				return nil, err
+			}
+			n.Elements = append(n.Elements, child)
+		}
+		// a nil *Identifier is not a nil Node, so only add the rest when there is one
+		if node.Rest != nil {
+			err = n.addChildren(map[string]Node{"rest": node.Rest})
+		}
+
+	case *HashPattern:
+		n.setToken(node.Token)
+		n.Pairs = make([]jsonPair, 0, len(node.Pairs))
+		for _, pair := range node.Pairs {
+			key, err := encodeNode(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := encodeNode(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			n.Pairs = append(n.Pairs, jsonPair{Key: key, Value: value})
+		}
+
+	case *Wildcard:
+		n.setToken(node.Token)
+
+	case *Identifier:
+		n.setToken(node.Token)
+		n.Value, err = json.Marshal(node.Value)
+
+	case *IntegerLiteral:
+		n.setToken(node.Token)
+		n.Value, err = json.Marshal(node.Value)
+
+	case *Boolean:
+		n.setToken(node.Token)
+		n.Value, err = json.Marshal(node.Value)
+
+	default:
+		return nil, fmt.Errorf("cannot encode node of type %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// encodeArm encodes the pattern, guard and body of arm. A guard is only there when the arm has one
+func encodeArm(arm *MatchArm) (jsonArm, error) {
+	var encoded jsonArm
+	var err error
+
+	if arm.Pattern != nil {
+		if encoded.Pattern, err = encodeNode(arm.Pattern); err != nil {
+			return jsonArm{}, err
+		}
+	}
+	if arm.Guard != nil {
+		if encoded.Guard, err = encodeNode(arm.Guard); err != nil {
+			return jsonArm{}, err
+		}
+	}
+	if arm.Body != nil {
+		if encoded.Body, err = encodeNode(arm.Body); err != nil {
+			return jsonArm{}, err
+		}
+	}
+
+	return encoded, nil
+}
+
+func (n *jsonNode) setToken(tok token.Token) {
+	position := tok.Position
+	n.Position = &position
+	n.Token = &jsonToken{Type: tok.Type, Literal: tok.Literal}
+}
+
+// addChildren encodes every child that is set. Children that are nil, like the value of a let statement that failed to parse, are left out
+func (n *jsonNode) addChildren(children map[string]Node) error {
+	for name, child := range children {
+		if child == nil {
+			continue
+		}
+
+		encoded, err := encodeNode(child)
+		if err != nil {
+			return err
+		}
+
+		if n.Children == nil {
+			n.Children = map[string]*jsonNode{}
+		}
+		n.Children[name] = encoded
+	}
+
+	return nil
+}
+
+func decodeNode(n *jsonNode) (Node, error) {
+	var tok token.Token
+	if n.Token != nil {
+		tok = token.Token{Type: n.Token.Type, Literal: n.Token.Literal}
+	}
+	if n.Position != nil {
+		tok.Position = *n.Position
+	}
+
+	switch n.Kind {
+	case "RootNode":
+		root := RootNode{Statements: make([]Statement, 0, len(n.Statements))}
+		for _, child := range n.Statements {
+			stmt, err := decodeChild[Statement](child)
+			if err != nil {
+				return nil, err
+			}
+			root.Statements = append(root.Statements, stmt)
+		}
+		return &root, nil
+
+	case "LetStatement":
+		name, err := decodeRequired[Pattern](n, "name")
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeChild[Expression](n.Children["value"])
+		if err != nil {
+			return nil, err
+		}
+		return &LetStatement{Token: tok, Name: name, Value: value}, nil
+
+	case "ReturnStatement":
+		value, err := decodeChild[Expression](n.Children["returnValue"])
+		if err != nil {
+			return nil, err
+		}
+		return &ReturnStatement{Token: tok, ReturnValue: value}, nil
+
+	case "ExpressionStatement":
+		exp, err := decodeChild[Expression](n.Children["expression"])
+		if err != nil {
+			return nil, err
+		}
+		return &ExpressionStatement{Token: tok, Expression: exp}, nil
+
+	case "PrefixExpression":
+		right, err := decodeRequired[Expression](n, "right")
+		if err != nil {
+			return nil, err
+		}
+		return &PrefixExpression{Token: tok, Operator: n.Operator, Right: right}, nil
+
+	case "InfixExpression":
+		left, err := decodeRequired[Expression](n, "left")
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeRequired[Expression](n, "right")
+		if err != nil {
+			return nil, err
+		}
+		return &InfixExpression{Token: tok, Operator: n.Operator, Left: left, Right: right}, nil
+
+	case "MatchExpression":
+		value, err := decodeRequired[Expression](n, "value")
+		if err != nil {
+			return nil, err
+		}
+		match := MatchExpression{Token: tok, Value: value, Arms: make([]*MatchArm, 0, len(n.Arms))}
+		for _, arm := range n.Arms {
+			if arm.Pattern == nil || arm.Body == nil {
+				return nil, fmt.Errorf("MatchExpression arm needs a pattern and a body")
+			}
+			pattern, err := decodeChild[Pattern](arm.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			guard, err := decodeChild[Expression](arm.Guard)
+			if err != nil {
+				return nil, err
+			}
+			body, err := decodeChild[Expression](arm.Body)
+			if err != nil {
+				return nil, err
+			}
+			match.Arms = append(match.Arms, &MatchArm{Pattern: pattern, Guard: guard, Body: body})
+		}
+		return &match, nil
+
+	case "StructStatement":
+		name, err := decodeRequired[*Identifier](n, "name")
+		if err != nil {
+			return nil, err
+		}
+		stmt := StructStatement{Token: tok, Name: name, Fields: make([]*Identifier, 0, len(n.Elements))}
+		for _, child := range n.Elements {
+			field, err := decodeChild[*Identifier](child)
+			if err != nil {
+				return nil, err
+			}
+			if field == nil {
+				return nil, fmt.Errorf("StructStatement field needs a name")
+			}
+			stmt.Fields = append(stmt.Fields, field)
+		}
+		return &stmt, nil
+
+	case "StructLiteral":
+		name, err := decodeRequired[*Identifier](n, "name")
+		if err != nil {
+			return nil, err
+		}
+		literal := StructLiteral{Token: tok, Name: name, Fields: make([]*StructField, 0, len(n.Pairs))}
+		for _, pair := range n.Pairs {
+			key, err := decodeChild[*Identifier](pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeChild[Expression](pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			if key == nil || value == nil {
+				return nil, fmt.Errorf("StructLiteral field needs a name and a value")
+			}
+			literal.Fields = append(literal.Fields, &StructField{Name: key, Value: value})
+		}
+		return &literal, nil
+
+	case "ArrayPattern":
+		pattern := ArrayPattern{Token: tok, Elements: make([]*Identifier, 0, len(n.Elements))}
+		for _, child := range n.Elements {
+			el, err := decodeChild[*Identifier](child)
+			if err != nil {
+				return nil, err
+			}
+			pattern.Elements = append(pattern.Elements, el)
+		}
+		if rest := n.Children["rest"]; rest != nil {
+			var err error
+			if pattern.Rest, err = decodeChild[*Identifier](rest); err != nil {
+				return nil, err
+			}
+		}
+		return &pattern, nil
+
+	case "HashPattern":
+		pattern := HashPattern{Token: tok, Pairs: make([]*HashPatternPair, 0, len(n.Pairs))}
+		for _, pair := range n.Pairs {
+			key, err := decodeChild[*Identifier](pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeChild[*Identifier](pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			if key == nil || value == nil {
+				return nil, fmt.Errorf("HashPattern pair needs a key and a value")
+			}
+			// the parser uses one identifier as key and value of {name}, keep it that way
+			if *key == *value {
+				value = key
+			}
+			pattern.Pairs = append(pattern.Pairs, &HashPatternPair{Key: key, Value: value})
+		}
+		return &pattern, nil
+
+	case "Wildcard":
+		return &Wildcard{Token: tok}, nil
+
+	case "Identifier":
+		ident := Identifier{Token: tok}
+		return &ident, decodeValue(n, &ident.Value)
+
+	case "IntegerLiteral":
+		integer := IntegerLiteral{Token: tok}
+		return &integer, decodeValue(n, &integer.Value)
+
+	case "Boolean":
+		boolean := Boolean{Token: tok}
+		return &boolean, decodeValue(n, &boolean.Value)
+	}
+
+	return nil, fmt.Errorf("unknown node kind %q", n.Kind)
+}
+
+// decodeChild decodes n and checks that it is a T. A missing child decodes to the zero value of T
+func decodeChild[T Node](n *jsonNode) (T, error) {
+	var zero T
+	if n == nil {
+		return zero, nil
+	}
+
+	node, err := decodeNode(n)
+	if err != nil {
+		return zero, err
+	}
+
+	child, ok := node.(T)
+	if !ok {
+		return zero, fmt.Errorf("%s cannot be used as %s", n.Kind, reflect.TypeFor[T]())
+	}
+
+	return child, nil
+}
+
+// decodeRequired decodes the child of n called name like decodeChild, but fails when it is missing because String would panic on the result
+func decodeRequired[T Node](n *jsonNode, name string) (T, error) {
+	child := n.Children[name]
+	if child == nil {
+		var zero T
+		return zero, fmt.Errorf("%s needs a %s", n.Kind, name)
+	}
+
+	return decodeChild[T](child)
+}
+
+func decodeValue(n *jsonNode, v any) error {
+	if n.Value == nil {
+		return fmt.Errorf("%s has no value", n.Kind)
+	}
+
+	if err := json.Unmarshal(n.Value, v); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", n.Kind, err)
+	}
+
+	return nil
+}
+
+// kindOf returns the name of the node's type without the package and pointer, e.g. InfixExpression
+func kindOf(node Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
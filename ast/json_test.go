@@ -0,0 +1,132 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ekediala/interpreter/ast"
+	"github.com/ekediala/interpreter/lexer"
+	"github.com/ekediala/interpreter/parser"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"let x = 5;",
+		"let [a, b, ...rest] = arr;",
+		"let [] = arr;",
+		"let {name, age: years} = person;",
+		"return 10;",
+		"foobar;",
+		"-a * b",
+		"!(true == false)",
+		"a + b * c + d / e - f",
+		"3 + 4; -5 * 5",
+		"5 > 4 == 3 < 4",
+		"(5 + 5) * 2 * (5 + 5)",
+		"match (x) { 0 => a, n if n > 10 => b, [first, ...rest] => first, {name} => name, _ => true }",
+		"match (x) {}",
+		"struct Point {x, y}",
+		"struct Empty {}",
+		"let p = Point{x: 1, y: -2};",
+		"Empty{}",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", input, p.Errors())
+		}
+
+		data, err := json.Marshal(program)
+		if err != nil {
+			t.Fatalf("json.Marshal(%q) failed: %s", input, err)
+		}
+
+		var decoded ast.RootNode
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+		}
+
+		if decoded.String() != program.String() {
+			t.Errorf("decoded.String() wrong; expected %q; got %q", program.String(), decoded.String())
+		}
+
+		if !reflect.DeepEqual(&decoded, program) {
+			t.Errorf("decoded tree for %q is not identical to the parsed tree", input)
+		}
+	}
+}
+
+func TestJSONRoundTripEveryNode(t *testing.T) {
+	for _, node := range everyNode() {
+		data, err := ast.EncodeJSON(node)
+		if err != nil {
+			t.Fatalf("EncodeJSON(%T) failed: %s", node, err)
+		}
+
+		decoded, err := ast.DecodeJSON(data)
+		if err != nil {
+			t.Fatalf("DecodeJSON(%s) failed: %s", data, err)
+		}
+
+		if !reflect.DeepEqual(decoded, node) {
+			t.Errorf("decoded %T is not identical to the original; got %s", node, data)
+		}
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	p := parser.New(lexer.New("-x"))
+	program := p.ParseProgram()
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"kind":"RootNode","statements":[` +
+		`{"kind":"ExpressionStatement","position":{"line":1,"column":1},"token":{"type":"-","literal":"-"},"children":{"expression":` +
+		`{"kind":"PrefixExpression","position":{"line":1,"column":1},"token":{"type":"-","literal":"-"},"operator":"-","children":{"right":` +
+		`{"kind":"Identifier","position":{"line":1,"column":2},"token":{"type":"IDENTIFIER","literal":"x"},"value":"x"}}}}}]}`
+
+	if string(data) != expected {
+		t.Errorf("json wrong;\nexpected %s\ngot      %s", expected, data)
+	}
+}
+
+func TestJSONDecodingErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`{"kind":"Nonsense"}`, `unknown node kind "Nonsense"`},
+		{`{"kind":"RootNode","statements":[{"kind":"Identifier","value":"x"}]}`, "Identifier cannot be used as ast.Statement"},
+		{`{"kind":"IntegerLiteral"}`, "IntegerLiteral has no value"},
+		{`{"kind":"Boolean","value":"yes"}`, "invalid value for Boolean"},
+		{`{"kind":"LetStatement"}`, "LetStatement needs a name"},
+		{`{"kind":"PrefixExpression","operator":"-"}`, "PrefixExpression needs a right"},
+		{`{"kind":"InfixExpression"}`, "InfixExpression needs a left"},
+		{`{"kind":"InfixExpression","children":{"left":{"kind":"Identifier","value":"x"}}}`, "InfixExpression needs a right"},
+		{`{"kind":"MatchExpression"}`, "MatchExpression needs a value"},
+		{`{"kind":"MatchExpression","children":{"value":{"kind":"Identifier","value":"x"}},"arms":[{"body":{"kind":"Identifier","value":"y"}}]}`, "MatchExpression arm needs a pattern and a body"},
+		{`{"kind":"MatchExpression","children":{"value":{"kind":"Identifier","value":"x"}},"arms":[{"pattern":{"kind":"Wildcard"}}]}`, "MatchExpression arm needs a pattern and a body"},
+		{`{"kind":"StructStatement","elements":[{"kind":"Identifier","value":"x"}]}`, "StructStatement needs a name"},
+		{`{"kind":"StructLiteral","children":{"name":{"kind":"Identifier","value":"Point"}},"pairs":[{"key":{"kind":"Identifier","value":"x"}}]}`, "StructLiteral field needs a name and a value"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.DecodeJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("DecodeJSON(%s) did not fail", tt.input)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tt.expectedError) {
+			t.Errorf("error for %s wrong; expected %q in %q", tt.input, tt.expectedError, err)
+		}
+	}
+}
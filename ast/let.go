@@ -8,7 +8,7 @@ import (
 
 type LetStatement struct {
 	Token token.Token // token.let
-	Name  *Identifier
+	Name  Pattern     // an *Identifier, or an *ArrayPattern or *HashPattern when destructuring
 	Value Expression
 }
 
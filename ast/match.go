@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/ekediala/interpreter/token"
+)
+
+// MatchExpression evaluates to the body of the first arm whose pattern matches the value, e.g. match (x) { 0 => a, n if n > 10 => b, _ => c }
+type MatchExpression struct {
+	Token token.Token // token.MATCH
+	Value Expression
+	Arms  []*MatchArm
+}
+
+// MatchArm is one pattern => body of a match expression. Guard is the condition after if, nil when the arm has none
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression
+	Body    Expression
+}
+
+func (m *MatchExpression) expressionNode() {}
+
+func (m *MatchExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MatchExpression) String() string {
+	var out strings.Builder
+
+	arms := make([]string, 0, len(m.Arms))
+	for _, arm := range m.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(m.Value.String())
+	out.WriteString(") {")
+	if len(arms) > 0 {
+		out.WriteString(" ")
+		out.WriteString(strings.Join(arms, ", "))
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (a *MatchArm) String() string {
+	var out strings.Builder
+
+	out.WriteString(a.Pattern.String())
+	if a.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(a.Guard.String())
+	}
+	out.WriteString(" " + token.ARROW + " ")
+	out.WriteString(a.Body.String())
+
+	return out.String()
+}
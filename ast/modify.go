@@ -0,0 +1,117 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ModifierFunc receives a node and returns the node that should take its place. Returning the node it was given leaves the tree untouched
+type ModifierFunc func(Node) Node
+
+// Modify rewrites node in place from the leaves up: the children of a node are modified before the node itself is handed to modifier, so modifier always sees children that have already been rewritten. Modify panics when modifier returns a node that cannot take the place of the old one, like a statement in place of an expression or anything but an identifier inside a pattern
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *RootNode:
+		for i, stmt := range n.Statements {
+			n.Statements[i] = modifyChild[Statement](n, stmt, modifier)
+		}
+
+	case *LetStatement:
+		if n.Name != nil {
+			n.Name = modifyChild[Pattern](n, n.Name, modifier)
+		}
+		if n.Value != nil {
+			n.Value = modifyChild[Expression](n, n.Value, modifier)
+		}
+
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			n.ReturnValue = modifyChild[Expression](n, n.ReturnValue, modifier)
+		}
+
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			n.Expression = modifyChild[Expression](n, n.Expression, modifier)
+		}
+
+	case *PrefixExpression:
+		if n.Right != nil {
+			n.Right = modifyChild[Expression](n, n.Right, modifier)
+		}
+
+	case *InfixExpression:
+		if n.Left != nil {
+			n.Left = modifyChild[Expression](n, n.Left, modifier)
+		}
+		if n.Right != nil {
+			n.Right = modifyChild[Expression](n, n.Right, modifier)
+		}
+
+	case *MatchExpression:
+		if n.Value != nil {
+			n.Value = modifyChild[Expression](n, n.Value, modifier)
+		}
+		for _, arm := range n.Arms {
+			if arm.Pattern != nil {
+				arm.Pattern = modifyChild[Pattern](n, arm.Pattern, modifier)
+			}
+			if arm.Guard != nil {
+				arm.Guard = modifyChild[Expression](n, arm.Guard, modifier)
+			}
+			if arm.Body != nil {
+				arm.Body = modifyChild[Expression](n, arm.Body, modifier)
+			}
+		}
+
+	case *StructStatement:
+		n.Name = modifyChild[*Identifier](n, n.Name, modifier)
+		for i, field := range n.Fields {
+			n.Fields[i] = modifyChild[*Identifier](n, field, modifier)
+		}
+
+	case *StructLiteral:
+		n.Name = modifyChild[*Identifier](n, n.Name, modifier)
+		for _, field := range n.Fields {
+			field.Name = modifyChild[*Identifier](n, field.Name, modifier)
+			field.Value = modifyChild[Expression](n, field.Value, modifier)
+		}
+
+	case *ArrayPattern:
+		for i, el := range n.Elements {
+			n.Elements[i] = modifyChild[*Identifier](n, el, modifier)
+		}
+		if n.Rest != nil {
+			n.Rest = modifyChild[*Identifier](n, n.Rest, modifier)
+		}
+
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			shorthand := pair.Value == pair.Key
+			pair.Key = modifyChild[*Identifier](n, pair.Key, modifier)
+			if shorthand {
+				pair.Value = pair.Key
+				continue
+			}
+			pair.Value = modifyChild[*Identifier](n, pair.Value, modifier)
+		}
+
+	case *Identifier, *IntegerLiteral, *Boolean, *Wildcard:
+		// nothing to do, these are leaves
+
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", n))
+	}
+
+	return modifier(node)
+}
+
+// modifyChild modifies child, a child of parent, and checks that the result can still be stored where child was
+func modifyChild[T Node](parent Node, child T, modifier ModifierFunc) T {
+	modified := Modify(child, modifier)
+	replacement, ok := modified.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Modify: %T needs a %s, but the modifier returned %T", parent, reflect.TypeFor[T](), modified))
+	}
+
+	return replacement
+}
@@ -0,0 +1,130 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/ekediala/interpreter/token"
+)
+
+// Pattern is what a let statement binds its value to: a plain identifier, or a pattern that destructures the value into several identifiers.
+// The arms of a match expression use patterns too, where literals and the wildcard _ are allowed as well
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// ArrayPattern destructures an array, e.g. the [a, b, ...rest] in let [a, b, ...rest] = arr;
+type ArrayPattern struct {
+	Token    token.Token // token.LBRACKET
+	Elements []*Identifier
+	Rest     *Identifier // binds the elements left over, nil when there is no ...rest
+}
+
+func (a *ArrayPattern) patternNode() {}
+
+func (a *ArrayPattern) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *ArrayPattern) String() string {
+	var out strings.Builder
+
+	elements := make([]string, 0, len(a.Elements)+1)
+	for _, el := range a.Elements {
+		elements = append(elements, el.String())
+	}
+	if a.Rest != nil {
+		elements = append(elements, token.ELLIPSIS+a.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPattern destructures a hash, e.g. the {name, age: years} in let {name, age: years} = person;
+type HashPattern struct {
+	Token token.Token // token.LBRACE
+	Pairs []*HashPatternPair
+}
+
+// HashPatternPair binds the value under Key to Value. In the shorthand {name}, Key and Value are both name
+type HashPatternPair struct {
+	Key   *Identifier
+	Value *Identifier
+}
+
+func (h *HashPattern) patternNode() {}
+
+func (h *HashPattern) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (h *HashPattern) String() string {
+	var out strings.Builder
+
+	pairs := make([]string, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		if pair.Key.Value == pair.Value.Value {
+			pairs = append(pairs, pair.Key.String())
+			continue
+		}
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Discard is the name of the identifier that binds nothing
+const Discard = "_"
+
+// Wildcard is the _ of a match arm. It matches any value and binds nothing
+type Wildcard struct {
+	Token token.Token // the _ identifier
+}
+
+func (w *Wildcard) patternNode() {}
+
+func (w *Wildcard) TokenLiteral() string {
+	return w.Token.Literal
+}
+
+func (w *Wildcard) String() string {
+	return w.Token.Literal
+}
+
+// BoundIdentifiers returns the identifiers a pattern binds, in the order they appear. An _ inside a pattern, like the one in [_, second], skips the value and binds nothing
+func BoundIdentifiers(pattern Pattern) []*Identifier {
+	var bound []*Identifier
+	bind := func(ident *Identifier) {
+		if ident.Value != Discard {
+			bound = append(bound, ident)
+		}
+	}
+
+	switch p := pattern.(type) {
+	case *Identifier:
+		bind(p)
+
+	case *ArrayPattern:
+		for _, el := range p.Elements {
+			bind(el)
+		}
+		if p.Rest != nil {
+			bind(p.Rest)
+		}
+
+	case *HashPattern:
+		for _, pair := range p.Pairs {
+			bind(pair.Value)
+		}
+	}
+
+	return bound
+}
@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/ekediala/interpreter/token"
+)
+
+// StructStatement declares a struct type and the names of its fields, e.g. struct Point {x, y}
+type StructStatement struct {
+	Token  token.Token // token.STRUCT
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (s *StructStatement) statementNode() {}
+
+func (s *StructStatement) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *StructStatement) String() string {
+	var out strings.Builder
+
+	fields := make([]string, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		fields = append(fields, field.String())
+	}
+
+	out.WriteString(s.TokenLiteral() + " ")
+	out.WriteString(s.Name.String())
+	out.WriteString(" {")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// StructLiteral builds a value of the struct type it names, e.g. Point{x: 1, y: 2}
+type StructLiteral struct {
+	Token  token.Token // token.LBRACE
+	Name   *Identifier
+	Fields []*StructField
+}
+
+// StructField sets the field Name of a struct literal to Value
+type StructField struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (s *StructLiteral) expressionNode() {}
+
+func (s *StructLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *StructLiteral) String() string {
+	var out strings.Builder
+
+	fields := make([]string, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		fields = append(fields, field.Name.String()+": "+field.Value.String())
+	}
+
+	out.WriteString(s.Name.String())
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,121 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If the result visitor w is not nil, Walk visits each of the children of node with the visitor w, followed by a call of w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order. It starts by calling v.Visit(node); node must not be nil. Children that are nil, like the value of a let statement that failed to parse, are not visited
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *RootNode:
+		for _, stmt := range n.Statements {
+			Walk(v, stmt)
+		}
+
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *MatchExpression:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		for _, arm := range n.Arms {
+			if arm.Pattern != nil {
+				Walk(v, arm.Pattern)
+			}
+			if arm.Guard != nil {
+				Walk(v, arm.Guard)
+			}
+			if arm.Body != nil {
+				Walk(v, arm.Body)
+			}
+		}
+
+	case *StructStatement:
+		Walk(v, n.Name)
+		for _, field := range n.Fields {
+			Walk(v, field)
+		}
+
+	case *StructLiteral:
+		Walk(v, n.Name)
+		for _, field := range n.Fields {
+			Walk(v, field.Name)
+			Walk(v, field.Value)
+		}
+
+	case *ArrayPattern:
+		for _, el := range n.Elements {
+			Walk(v, el)
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			Walk(v, pair.Key)
+			// {name} uses the same identifier as key and value, do not visit it twice
+			if pair.Value != pair.Key {
+				Walk(v, pair.Value)
+			}
+		}
+
+	case *Identifier, *IntegerLiteral, *Boolean, *Wildcard:
+		// nothing to do, these are leaves
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order. It starts by calling f(node); node must not be nil. If f returns true, Inspect invokes f recursively for each of the non-nil children of node, followed by a call of f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,290 @@
+package ast_test
+
+import (
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ekediala/interpreter/ast"
+	"github.com/ekediala/interpreter/token"
+)
+
+// every node type in package ast, each with all of its children set. TestEveryNodeIsHandled fails when a node type is missing from this list
+func everyNode() []ast.Node {
+	ident := func(name string) *ast.Identifier {
+		return &ast.Identifier{Token: token.Token{Type: token.IDENTIFIER, Literal: name}, Value: name}
+	}
+	integer := &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "5"}, Value: 5}
+	boolean := &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	prefix := &ast.PrefixExpression{Token: token.Token{Type: token.MINUS, Literal: "-"}, Operator: "-", Right: integer}
+	infix := &ast.InfixExpression{Token: token.Token{Type: token.PLUS, Literal: "+"}, Operator: "+", Left: ident("x"), Right: integer}
+	let := &ast.LetStatement{Token: token.Token{Type: token.LET, Literal: "let"}, Name: ident("y"), Value: infix}
+	ret := &ast.ReturnStatement{Token: token.Token{Type: token.RETURN, Literal: "return"}, ReturnValue: boolean}
+	exp := &ast.ExpressionStatement{Token: prefix.Token, Expression: prefix}
+	array := &ast.ArrayPattern{Token: token.Token{Type: token.LBRACKET, Literal: "["}, Elements: []*ast.Identifier{ident("a")}, Rest: ident("rest")}
+	name := ident("name")
+	hash := &ast.HashPattern{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Pairs: []*ast.HashPatternPair{{Key: name, Value: name}, {Key: ident("age"), Value: ident("years")}}}
+	wildcard := &ast.Wildcard{Token: token.Token{Type: token.IDENTIFIER, Literal: "_"}}
+	match := &ast.MatchExpression{Token: token.Token{Type: token.MATCH, Literal: "match"}, Value: ident("v"), Arms: []*ast.MatchArm{
+		{Pattern: integer, Body: ident("a")},
+		{Pattern: ident("n"), Guard: infix, Body: ident("b")},
+		{Pattern: wildcard, Body: boolean},
+	}}
+	declaration := &ast.StructStatement{Token: token.Token{Type: token.STRUCT, Literal: "struct"}, Name: ident("Point"), Fields: []*ast.Identifier{ident("x"), ident("y")}}
+	literal := &ast.StructLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Name: ident("Point"), Fields: []*ast.StructField{{Name: ident("x"), Value: integer}, {Name: ident("y"), Value: prefix}}}
+
+	return []ast.Node{
+		&ast.RootNode{Statements: []ast.Statement{let, ret, exp}},
+		let, ret, exp, prefix, infix, ident("z"), integer, boolean, array, hash, match, wildcard, declaration, literal,
+	}
+}
+
+func TestEveryNodeIsHandled(t *testing.T) {
+	declared := declaredNodeTypes(t)
+
+	var listed []string
+	for _, node := range everyNode() {
+		listed = append(listed, reflect.TypeOf(node).Elem().Name())
+
+		// neither function may panic on a node it knows about
+		ast.Inspect(node, func(ast.Node) bool { return true })
+		ast.Modify(node, func(n ast.Node) ast.Node { return n })
+	}
+
+	for _, name := range declared {
+		if !slices.Contains(listed, name) {
+			t.Errorf("node type %s is not covered by everyNode(); add it there and handle it in Walk and Modify", name)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	root := everyNode()[0]
+
+	var visited []string
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n != nil {
+			visited = append(visited, fmt.Sprintf("%T", n))
+		}
+		return true
+	})
+
+	expected := []string{
+		"*ast.RootNode",
+		"*ast.LetStatement", "*ast.Identifier", "*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+		"*ast.ReturnStatement", "*ast.Boolean",
+		"*ast.ExpressionStatement", "*ast.PrefixExpression", "*ast.IntegerLiteral",
+	}
+
+	if !slices.Equal(visited, expected) {
+		t.Errorf("visit order wrong;\nexpected %v\ngot      %v", expected, visited)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	root := everyNode()[0]
+
+	var visited int
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		visited++
+		_, isLet := n.(*ast.LetStatement)
+		return !isLet
+	})
+
+	// the let statement's name, infix, identifier and integer are skipped
+	if visited != 7 {
+		t.Errorf("expected 7 visited nodes; got %d", visited)
+	}
+}
+
+func TestInspectPatterns(t *testing.T) {
+	tests := []struct {
+		input    ast.Node
+		expected []string
+	}{
+		{everyNode()[9], []string{"[", "a", "rest"}},
+		// the shorthand {name} is visited once
+		{everyNode()[10], []string{"{", "name", "age", "years"}},
+		// every arm is visited pattern first, then its guard and body
+		{everyNode()[11], []string{"match", "v", "5", "a", "n", "+", "x", "5", "b", "_", "true"}},
+		{everyNode()[13], []string{"struct", "Point", "x", "y"}},
+		{everyNode()[14], []string{"{", "Point", "x", "5", "y", "-", "5"}},
+	}
+
+	for _, tt := range tests {
+		var visited []string
+		ast.Inspect(tt.input, func(n ast.Node) bool {
+			if n != nil {
+				visited = append(visited, n.TokenLiteral())
+			}
+			return true
+		})
+
+		if !slices.Equal(visited, tt.expected) {
+			t.Errorf("visited wrong for %s; expected %v, got %v", tt.input, tt.expected, visited)
+		}
+	}
+}
+
+func TestModify(t *testing.T) {
+	one := func() ast.Expression {
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1}
+	}
+	two := func() ast.Expression {
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "2"}, Value: 2}
+	}
+
+	turnOneIntoTwo := func(node ast.Node) ast.Node {
+		integer, ok := node.(*ast.IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return two()
+	}
+
+	tests := []struct {
+		input    ast.Node
+		expected string
+	}{
+		{one(), "2"},
+		{&ast.RootNode{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: one()}}}, "2"},
+		{&ast.InfixExpression{Left: one(), Operator: "+", Right: two()}, "(2 + 2)"},
+		{&ast.InfixExpression{Left: two(), Operator: "+", Right: one()}, "(2 + 2)"},
+		{&ast.PrefixExpression{Operator: "-", Right: one()}, "(-2)"},
+		{&ast.ReturnStatement{Token: token.Token{Type: token.RETURN, Literal: "return"}, ReturnValue: one()}, "return 2;"},
+		{&ast.LetStatement{Token: token.Token{Type: token.LET, Literal: "let"}, Name: &ast.Identifier{Value: "x"}, Value: one()}, "let x = 2;"},
+		{&ast.LetStatement{Token: token.Token{Type: token.LET, Literal: "let"}, Name: &ast.ArrayPattern{Elements: []*ast.Identifier{{Value: "x"}}}, Value: one()}, "let [x] = 2;"},
+	}
+
+	for _, tt := range tests {
+		modified := ast.Modify(tt.input, turnOneIntoTwo)
+		if modified.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, modified.String())
+		}
+	}
+}
+
+// declaredNodeTypes reads the source of package ast and returns the name of every type that has a TokenLiteral method
+func declaredNodeTypes(t *testing.T) []string {
+	t.Helper()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	fset := gotoken.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := goparser.ParseFile(fset, file, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*goast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+				continue
+			}
+
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*goast.StarExpr); ok {
+				recv = star.X
+			}
+			names = append(names, recv.(*goast.Ident).Name)
+		}
+	}
+
+	if len(names) == 0 {
+		t.Fatal("found no node types in package ast")
+	}
+
+	return names
+}
+
+func TestModifyPatterns(t *testing.T) {
+	rename := func(node ast.Node) ast.Node {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		return &ast.Identifier{Token: ident.Token, Value: ident.Value + "2"}
+	}
+
+	name := &ast.Identifier{Value: "name"}
+	hash := &ast.HashPattern{Pairs: []*ast.HashPatternPair{{Key: name, Value: name}, {Key: &ast.Identifier{Value: "age"}, Value: &ast.Identifier{Value: "years"}}}}
+	array := &ast.ArrayPattern{Elements: []*ast.Identifier{{Value: "a"}}, Rest: &ast.Identifier{Value: "rest"}}
+
+	if modified := ast.Modify(array, rename).String(); modified != "[a2, ...rest2]" {
+		t.Errorf("expected=%q, got=%q", "[a2, ...rest2]", modified)
+	}
+
+	if modified := ast.Modify(hash, rename).String(); modified != "{name2, age2: years2}" {
+		t.Errorf("expected=%q, got=%q", "{name2, age2: years2}", modified)
+	}
+
+	if hash.Pairs[0].Key != hash.Pairs[0].Value {
+		t.Errorf("the key and value of the shorthand {name} should still be the same identifier")
+	}
+}
+
+func TestModifyWrongKind(t *testing.T) {
+	tests := []struct {
+		node          ast.Node
+		replacement   ast.Node
+		expectedPanic string
+	}{
+		{
+			&ast.ExpressionStatement{Expression: &ast.Identifier{Value: "x"}},
+			&ast.LetStatement{},
+			"ast.Modify: *ast.ExpressionStatement needs a ast.Expression, but the modifier returned *ast.LetStatement",
+		},
+		{
+			&ast.ArrayPattern{Elements: []*ast.Identifier{{Value: "x"}}},
+			&ast.IntegerLiteral{Value: 1},
+			"ast.Modify: *ast.ArrayPattern needs a *ast.Identifier, but the modifier returned *ast.IntegerLiteral",
+		},
+		{
+			&ast.RootNode{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: &ast.Identifier{Value: "x"}}}},
+			nil,
+			"ast.Modify: *ast.ExpressionStatement needs a ast.Expression, but the modifier returned <nil>",
+		},
+	}
+
+	for _, tt := range tests {
+		replace := func(node ast.Node) ast.Node {
+			if _, ok := node.(*ast.Identifier); ok {
+				return tt.replacement
+			}
+			return node
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r != tt.expectedPanic {
+					t.Errorf("expected panic %q, got %v", tt.expectedPanic, r)
+				}
+			}()
+			ast.Modify(tt.node, replace)
+		}()
+	}
+}
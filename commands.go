@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ekediala/interpreter/lexer"
+	"github.com/ekediala/interpreter/lsp"
+	"github.com/ekediala/interpreter/parser"
+)
+
+// commands run instead of the REPL when their name is the first argument
+var commands = map[string]func(args []string, out io.Writer) error{
+	"ast": astCommand,
+	"lsp": lspCommand,
+}
+
+func runCommand(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return command(args, os.Stdout)
+}
+
+// astCommand prints the tree the parser produces for a file, either as source or as JSON
+func astCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("ast", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the tree as JSON")
+	trace := fs.Bool("trace", false, "write the parse functions the parser steps through to stderr")
+	traceJSON := fs.Bool("trace-json", false, "like -trace but write one JSON record per step")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: ast [--json] [--trace | --trace-json] <file>")
+	}
+
+	var opts []parser.Option
+	if *trace {
+		opts = append(opts, parser.WithTrace(os.Stderr))
+	}
+	if *traceJSON {
+		opts = append(opts, parser.WithTraceHandler(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+
+	source, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	p := parser.New(lexer.NewReader(source), opts...)
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		var msgs []string
+		for _, err := range p.ParseErrors() {
+			msgs = append(msgs, fmt.Sprintf("%s:%s", fs.Arg(0), err))
+		}
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+
+	if !*asJSON {
+		_, err := fmt.Fprintln(out, program.String())
+		return err
+	}
+
+	data, err := json.MarshalIndent(program, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// lspCommand serves the Language Server Protocol over stdin and stdout for editors
+func lspCommand(args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return errors.New("usage: lsp")
+	}
+
+	return lsp.NewServer(os.Stdin, out).Serve()
+}
@@ -0,0 +1,257 @@
+package format
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ekediala/interpreter/lexer"
+	"github.com/ekediala/interpreter/token"
+)
+
+const indentation = "\t"
+
+// Source lays source out the canonical way: one statement per line, blocks indented with tabs, single spaces around infix operators and none after prefix operators.
+// It works on tokens rather than the tree so it only ever changes whitespace, even for source the parser cannot handle yet
+func Source(source string) (string, error) {
+	l := lexer.New(source)
+	tokens := slices.Collect(l.Tokens())
+	if err := l.Err(); err != nil {
+		return "", err
+	}
+
+	f := formatter{}
+	for i, tok := range tokens {
+		var prev token.Token
+		if i > 0 {
+			prev = tokens[i-1]
+		}
+		f.write(prev, tok, i == 0)
+	}
+
+	formatted := strings.TrimRight(f.out.String(), "\n \t")
+	if formatted != "" {
+		formatted += "\n"
+	}
+
+	// only whitespace may change. if the formatted source lexes differently, two tokens were glued together into another one, say = and = into ==
+	if !sameTokens(tokens, formatted) {
+		return "", fmt.Errorf("formatting changed the meaning of the source")
+	}
+
+	return formatted, nil
+}
+
+type formatter struct {
+	out         strings.Builder
+	indent      int
+	parens      int     // how many ( we are inside of. statements do not end inside parentheses
+	brackets    int     // how many [ we are inside of. the commas of an array pattern in a match arm do not end the arm
+	atLineStart bool    // nothing has been written on the current line yet
+	pending     bool    // the previous token wants to end the line, unless the next one belongs on it like the else after }
+	prefix      bool    // the previous token was a prefix operator
+	matches     int     // how many match keywords are still waiting for the { of their arms
+	structs     bool    // a struct keyword is waiting for the { of its fields
+	literal     bool    // the previous token was the } of a struct literal, which ends an operand like an identifier does
+	braces      []brace // every { we are inside of
+}
+
+// brace is a { we are inside of, along with how many ( and [ were open when we got to it
+type brace struct {
+	kind     braceKind
+	parens   int
+	brackets int
+}
+
+type braceKind int
+
+const (
+	patternBrace braceKind = iota // the { of a pattern like let {name} = person; stays on its line
+	blockBrace
+	armsBrace    // the { of a match expression, every arm goes on a line of its own
+	fieldsBrace  // the { of a struct declaration, the fields stay on its line and the declaration ends at the }
+	literalBrace // the { of a struct literal, which hugs the name of the struct like Point{x: 1}
+)
+
+// inline reports whether everything between the braces stays on one line
+func (k braceKind) inline() bool {
+	return k != blockBrace && k != armsBrace
+}
+
+// betweenArms reports whether we are directly inside the arms of a match expression, where a comma ends an arm. Commas inside a ( or [ opened within an arm belong to the arm, those opened before the match do not matter
+func (f *formatter) betweenArms() bool {
+	if len(f.braces) == 0 {
+		return false
+	}
+
+	innermost := f.braces[len(f.braces)-1]
+	return innermost.kind == armsBrace && f.parens == innermost.parens && f.brackets == innermost.brackets
+}
+
+func (f *formatter) write(prev, tok token.Token, first bool) {
+	var closed braceKind
+	closesBlock := false
+	if tok.Type == token.RBRACE && len(f.braces) > 0 {
+		closed = f.braces[len(f.braces)-1].kind
+		closesBlock = !closed.inline()
+		f.braces = f.braces[:len(f.braces)-1]
+	}
+
+	startsLiteral := tok.Type == token.LBRACE && prev.Type == token.IDENTIFIER && !f.structs
+
+	if closesBlock {
+		f.indent = max(f.indent-1, 0)
+	}
+
+	switch {
+	case first:
+		// nothing comes before the first token
+
+	case f.pending && prev.Type == token.RBRACE && tok.Type == token.ELSE:
+		f.out.WriteString(" ")
+
+	case f.pending && prev.Type == token.RBRACE && (tok.Type == token.SEMICOLON || tok.Type == token.RPAREN || tok.Type == token.COMMA):
+		// } else, }; and friends stay together
+
+	case f.pending && prev.Type == token.LBRACE && tok.Type == token.RBRACE:
+		// keep empty blocks on one line
+
+	case f.pending:
+		f.newline(blankLines(prev, tok))
+
+	case closesBlock:
+		f.newline(0)
+
+	case (endsOperand(prev.Type) || f.literal) && startsStatement(tok.Type) && tok.Position.Line > prev.Position.Line && f.parens == 0:
+		// the parser reads "a\nb" as two statements without a semicolon between them, keep them on separate lines
+		f.newline(blankLines(prev, tok))
+
+	case startsLiteral:
+		// no space between the name of a struct and the { of a literal
+
+	case needsSpace(prev, tok, f.prefix):
+		f.out.WriteString(" ")
+	}
+
+	f.pending = false
+	if f.atLineStart {
+		f.out.WriteString(strings.Repeat(indentation, f.indent))
+		f.atLineStart = false
+	}
+
+	f.prefix = isPrefix(prev, tok, first)
+	f.literal = tok.Type == token.RBRACE && closed == literalBrace
+	f.out.WriteString(tok.Literal)
+
+	switch tok.Type {
+	case token.LPAREN:
+		f.parens++
+	case token.RPAREN:
+		f.parens = max(f.parens-1, 0)
+	case token.LBRACKET:
+		f.brackets++
+	case token.RBRACKET:
+		f.brackets = max(f.brackets-1, 0)
+	case token.SEMICOLON:
+		f.pending = f.parens == 0
+	case token.COMMA:
+		f.pending = f.betweenArms()
+	case token.MATCH:
+		f.matches++
+	case token.STRUCT:
+		f.structs = true
+	case token.LBRACE:
+		kind := blockBrace
+		switch {
+		case f.structs:
+			kind = fieldsBrace
+			f.structs = false
+		case startsLiteral:
+			kind = literalBrace
+		case f.matches > 0:
+			kind = armsBrace
+			f.matches--
+		case prev.Type == token.LET && !first, f.betweenArms() && (prev.Type == token.LBRACE || prev.Type == token.COMMA):
+			// a hash pattern after let or at the start of a match arm
+			kind = patternBrace
+		}
+		f.braces = append(f.braces, brace{kind: kind, parens: f.parens, brackets: f.brackets})
+		if !kind.inline() {
+			f.indent++
+			f.pending = true
+		}
+	case token.RBRACE:
+		f.pending = closesBlock || closed == fieldsBrace
+	}
+}
+
+func (f *formatter) newline(blank int) {
+	f.out.WriteString("\n")
+	f.out.WriteString(strings.Repeat("\n", blank))
+	f.atLineStart = true
+}
+
+// blankLines keeps at most one blank line the author put between two statements. blank lines right after { are dropped
+func blankLines(prev, tok token.Token) int {
+	if prev.Type != token.LBRACE && tok.Position.Line-prev.Position.Line > 1 {
+		return 1
+	}
+	return 0
+}
+
+func needsSpace(prev, tok token.Token, prevIsPrefix bool) bool {
+	switch {
+	case tok.Type == token.SEMICOLON, tok.Type == token.COMMA, tok.Type == token.RPAREN, tok.Type == token.RBRACKET, tok.Type == token.COLON:
+		return false
+	case prev.Type == token.LBRACKET, prev.Type == token.ELLIPSIS:
+		return false
+	case prev.Type == token.LBRACE, tok.Type == token.RBRACE:
+		// only reached inside patterns and structs, blocks put their braces on lines of their own
+		return false
+	case prevIsPrefix:
+		// ! followed by = or == would read as != once glued together
+		return tok.Type == token.ASSIGN || tok.Type == token.EQ
+	case prev.Type == token.LPAREN:
+		return false
+	case tok.Type == token.LPAREN:
+		// calls and function literals hug their parentheses, if (…) and grouped expressions after an operator do not
+		return prev.Type != token.IDENTIFIER && prev.Type != token.RPAREN && prev.Type != token.FUNCTION
+	}
+
+	return true
+}
+
+// isPrefix reports whether tok is a prefix operator, i.e. a ! or a - that does not follow an operand
+func isPrefix(prev, tok token.Token, first bool) bool {
+	if tok.Type != token.BANG && tok.Type != token.MINUS {
+		return false
+	}
+	return first || !endsOperand(prev.Type)
+}
+
+func endsOperand(t token.TokenType) bool {
+	switch t {
+	case token.IDENTIFIER, token.INT, token.TRUE, token.FALSE, token.RPAREN:
+		return true
+	}
+	return false
+}
+
+func startsStatement(t token.TokenType) bool {
+	switch t {
+	case token.IDENTIFIER, token.INT, token.TRUE, token.FALSE, token.BANG, token.LPAREN, token.LET, token.RETURN, token.IF, token.FUNCTION, token.MATCH, token.STRUCT:
+		return true
+	}
+	return false
+}
+
+func sameTokens(tokens []token.Token, formatted string) bool {
+	i := 0
+	for tok := range lexer.New(formatted).Tokens() {
+		if i >= len(tokens) || tokens[i].Type != tok.Type || tokens[i].Literal != tok.Literal {
+			return false
+		}
+		i++
+	}
+	return i == len(tokens)
+}
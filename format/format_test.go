@@ -0,0 +1,66 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/ekediala/interpreter/format"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"   \n\t\n", ""},
+		{"let x=5;let y =   10;", "let x = 5;\nlet y = 10;\n"},
+		{"let x = 5;\n\n\n\nlet y = 10;", "let x = 5;\n\nlet y = 10;\n"},
+		{"-a*b", "-a * b\n"},
+		{"a - -b", "a - -b\n"},
+		{"return -1;", "return -1;\n"},
+		{"!( true==true )", "!(true == true)\n"},
+		{"a\nb", "a\nb\n"},
+		{"a\n-b", "a - b\n"},
+		{"x\n(y)", "x\n(y)\n"},
+		{"(a)\n(b)", "(a)\n(b)\n"},
+		{"let add = fn(x,y){x+y;};", "let add = fn(x, y) {\n\tx + y;\n};\n"},
+		{"let result = add( five , ten );", "let result = add(five, ten);\n"},
+		{"if(5<10){return true;}else{return false;}", "if (5 < 10) {\n\treturn true;\n} else {\n\treturn false;\n}\n"},
+		{"if (x) {\n\n  if (y) { z }\n}", "if (x) {\n\tif (y) {\n\t\tz\n\t}\n}\n"},
+		{"let f = fn() {};", "let f = fn() {};\n"},
+		{"! = x", "! = x\n"},
+		{"let [ a,b , ... rest ]=arr;", "let [a, b, ...rest] = arr;\n"},
+		{"let {name,age : years}=person;let {}=x;", "let {name, age: years} = person;\nlet {} = x;\n"},
+		{"if (x) { let {a} = y; }", "if (x) {\n\tlet {a} = y;\n}\n"},
+		{"match(x){0=>a,n if n>10=>b,_=>c}", "match (x) {\n\t0 => a,\n\tn if n > 10 => b,\n\t_ => c\n}\n"},
+		{"let y = match (x) { [a, ...b] => a, {name} => name, };", "let y = match (x) {\n\t[a, ...b] => a,\n\t{name} => name,\n};\n"},
+		{"match (x) { 1 => match (y) { _ => 2 }, _ => 3 }", "match (x) {\n\t1 => match (y) {\n\t\t_ => 2\n\t},\n\t_ => 3\n}\n"},
+		{"match (x) {}", "match (x) {}\n"},
+		{"f(match (x) { _ => 1, 2 => 3 })", "f(match (x) {\n\t_ => 1,\n\t2 => 3\n})\n"},
+		{"f(a, match (x) { {name} => g(1, 2), [b, c] => b })", "f(a, match (x) {\n\t{name} => g(1, 2),\n\t[b, c] => b\n})\n"},
+		{"struct Point { x,y }struct Empty{};", "struct Point {x, y}\nstruct Empty {};\n"},
+		{"let p=Point {x:1,y:Line{from:a}};", "let p = Point{x: 1, y: Line{from: a}};\n"},
+		{"Point{x: 1}\nPoint{x: 2}", "Point{x: 1}\nPoint{x: 2}\n"},
+		{"match (p) { {x} => Point{x: x, y: 0}, _ => p }", "match (p) {\n\t{x} => Point{x: x, y: 0},\n\t_ => p\n}\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := format.Source(tt.input)
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", tt.input, err)
+		}
+
+		if formatted != tt.expected {
+			t.Errorf("Source(%q) wrong;\nexpected %q\ngot      %q", tt.input, tt.expected, formatted)
+		}
+
+		again, err := format.Source(formatted)
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", formatted, err)
+		}
+
+		if again != formatted {
+			t.Errorf("formatting %q twice changed it;\nfirst  %q\nsecond %q", tt.input, formatted, again)
+		}
+	}
+}
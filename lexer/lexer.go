@@ -1,26 +1,70 @@
 package lexer
 
-import "github.com/ekediala/interpreter/token"
+import (
+	"bufio"
+	"io"
+	"iter"
+	"strings"
+
+	"github.com/ekediala/interpreter/token"
+)
 
 type Lexer struct {
-	input        string
-	position     int  // current read position in input. should point to the current character under evaluation.
-	nextPosition int  // next position after position to be read and lexed
-	ch           byte // current character under evaluation
+	input  io.RuneReader
+	ch     rune  // current character under evaluation
+	next   rune  // character after ch. we read one character ahead so we can peek without holding on to the whole input
+	line   int   // line of ch in input, starting at 1
+	column int   // column of ch in line, starting at 1. counts characters, not bytes
+	err    error // first error we got reading input, other than io.EOF
 }
 
 // Reads the next character into l.ch and advances our cursor in the input
 func (l *Lexer) ReadNextChar() {
-	// advance cursors. why advance cursors with defer and not at the end of the function while using an else block to handle nextPosition being less than lenth of input? I just don't like else statements. I find that they make my code harder for me to follow. I prefer early returns.
-	defer l.advancePositions()
+	// ch is about to be replaced by the character after it, so move past ch in the source
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+
+	l.ch = l.next
+	l.next = l.readRune()
+}
+
+// reads a single character from input. If we are at the end of the input, or we could not read it, return zero [ASCII for "NUL"] so we can identify that as the end of lexing
+func (l *Lexer) readRune() rune {
+	if l.err != nil {
+		return 0
+	}
 
-	// prevent indexing out of array. If we are at the end of the input, set ch to zero [ASCII for "NUL"] so we can identify that as the end of lexing
-	if l.nextPosition >= len(l.input) {
-		l.ch = 0
-		return
+	ch, _, err := l.input.ReadRune()
+	if err == io.EOF {
+		return 0
 	}
 
-	l.ch = l.input[l.nextPosition]
+	if err != nil {
+		l.err = err
+		return 0
+	}
+
+	return ch
+}
+
+// Err returns the error that stopped the lexer from reading its input, if any. Reaching the end of the input is not an error
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// Tokens returns an iterator over the remaining tokens. It stops before the EOF token
+func (l *Lexer) Tokens() iter.Seq[token.Token] {
+	return func(yield func(token.Token) bool) {
+		for tok := l.ReadAndAdvanceToken(); tok.Type != token.EOF; tok = l.ReadAndAdvanceToken() {
+			if !yield(tok) {
+				return
+			}
+		}
+	}
 }
 
 // Returns current token and advances the cursor
@@ -29,6 +73,8 @@ func (l *Lexer) ReadAndAdvanceToken() token.Token {
 
 	l.skipWhitespace()
 
+	position := token.Position{Line: l.line, Column: l.column}
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -36,6 +82,11 @@ func (l *Lexer) ReadAndAdvanceToken() token.Token {
 			l.ReadNextChar()
 			tok.Literal = string(ch) + string(l.ch)
 			tok.Type = token.EQ
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.ReadNextChar()
+			tok.Literal = string(ch) + string(l.ch)
+			tok.Type = token.ARROW
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -54,6 +105,14 @@ func (l *Lexer) ReadAndAdvanceToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '.':
+		tok = l.readEllipsis()
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -80,11 +139,13 @@ func (l *Lexer) ReadAndAdvanceToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdentifier(tok.Literal)
+			tok.Position = position
 			// we have already advanced past the last character of the identifier, so return here to avoid calling ReadNextChar again
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Literal = l.readNumber()
 			tok.Type = token.INT
+			tok.Position = position
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -92,40 +153,49 @@ func (l *Lexer) ReadAndAdvanceToken() token.Token {
 
 	}
 
+	tok.Position = position
 	l.ReadNextChar()
 
 	return tok
 }
 
-func (l *Lexer) readIdentifier() string {
-	currentPosition := l.position
-	for isLetter(l.ch) {
+// we only understand dots as part of ..., so anything shorter is illegal. leaves ch on the last dot read like the two character operators do
+func (l *Lexer) readEllipsis() token.Token {
+	literal := string(l.ch)
+	for len(literal) < len(token.ELLIPSIS) && l.peekChar() == '.' {
 		l.ReadNextChar()
+		literal += string(l.ch)
 	}
 
-	return l.input[currentPosition:l.position]
+	if literal != token.ELLIPSIS {
+		return token.Token{Type: token.ILLEGAL, Literal: literal}
+	}
+
+	return token.Token{Type: token.ELLIPSIS, Literal: literal}
 }
 
-func (l *Lexer) readNumber() string {
-	currentPosition := l.position
-	for isDigit(l.ch) {
+func (l *Lexer) readIdentifier() string {
+	var identifier strings.Builder
+	for isLetter(l.ch) {
+		identifier.WriteRune(l.ch)
 		l.ReadNextChar()
 	}
 
-	return l.input[currentPosition:l.position]
+	return identifier.String()
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.nextPosition >= len(l.input) {
-		return 0
+func (l *Lexer) readNumber() string {
+	var number strings.Builder
+	for isDigit(l.ch) {
+		number.WriteRune(l.ch)
+		l.ReadNextChar()
 	}
 
-	return l.input[l.nextPosition]
+	return number.String()
 }
 
-func (l *Lexer) advancePositions() {
-	l.position = l.nextPosition
-	l.nextPosition += 1
+func (l *Lexer) peekChar() rune {
+	return l.next
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -134,35 +204,40 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func newToken(t token.TokenType, ch byte) token.Token {
+func newToken(t token.TokenType, ch rune) token.Token {
 	return token.Token{
 		Type:    t,
 		Literal: string(ch),
 	}
 }
 
-func isLetter(ch byte) bool {
+func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
 func New(source string) *Lexer {
+	return NewReader(strings.NewReader(source))
+}
+
+// NewReader lexes source as it is read instead of needing all of it up front. It produces the same tokens and positions New would for the same source
+func NewReader(source io.Reader) *Lexer {
+	input, ok := source.(io.RuneReader)
+	if !ok {
+		input = bufio.NewReader(source)
+	}
+
 	l := Lexer{
-		input: source,
+		input: input,
+		line:  1,
 	}
 
-	// initialise position, nextPosition, and ch to their start points. will set ch to l.input[0] as zero value of nextPosition is 0, set position to 0[unnecessary as it is already 0] and then set nextPosition to 1
+	// fill next, then move it into ch. this sets ch to the first character, next to the second and the column to 1
+	l.next = l.readRune()
 	l.ReadNextChar()
 
-	// we can eliminate the call above and initialise the lexer with default values like below:
-	// 	l := Lexer{
-	// 	input:        source,
-	// 	nextPosition: 1,
-	// 	ch:           source[0],
-	// }
-	// will save us a function call, no checks for length of string, no setting position to 0 when it is already zero. I know which should be faster but I don't know which is better. I find hardcoding values weird.
 	return &l
 }
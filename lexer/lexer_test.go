@@ -1,7 +1,12 @@
 package lexer_test
 
 import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/ekediala/interpreter/lexer"
 	"github.com/ekediala/interpreter/token"
@@ -124,3 +129,278 @@ if (5 < 10) {
 
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	source := `let five = 5;
+  five == 10;
+
+	!five`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"five", 1, 5},
+		{"=", 1, 10},
+		{"5", 1, 12},
+		{";", 1, 13},
+		{"five", 2, 3},
+		{"==", 2, 8},
+		{"10", 2, 11},
+		{";", 2, 13},
+		{"!", 4, 2},
+		{"five", 4, 3},
+		{"", 4, 7},
+	}
+
+	l := lexer.New(source)
+
+	for i, tt := range tests {
+		tok := l.ReadAndAdvanceToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Position.Line != tt.expectedLine || tok.Position.Column != tt.expectedColumn {
+			t.Fatalf("test[%d] - position wrong, expected=%d:%d, got=%s", i, tt.expectedLine, tt.expectedColumn, tok.Position)
+		}
+	}
+}
+
+func TestNewReaderProducesSameTokens(t *testing.T) {
+	sources := []string{
+		"",
+		"let five = 5;\nlet ten = 10;\n\nlet add = fn(x, y) {\n  x + y;\n};",
+		"if (5 < 10) {\n\treturn true;\n} else {\n\treturn false;\n}\n10 == 10;\n10 != 9;",
+		"let café = \"naïve\"; é + ü\n€",
+	}
+
+	for _, source := range sources {
+		expected := lexer.New(source)
+		// OneByteReader is not an io.RuneReader, so this also covers the buffered path and runes split across reads
+		got := lexer.NewReader(iotest.OneByteReader(strings.NewReader(source)))
+
+		for i := 0; ; i++ {
+			expectedTok := expected.ReadAndAdvanceToken()
+			gotTok := got.ReadAndAdvanceToken()
+
+			if gotTok != expectedTok {
+				t.Fatalf("source %q token[%d] wrong, expected=%+v, got=%+v", source, i, expectedTok, gotTok)
+			}
+
+			if expectedTok.Type == token.EOF {
+				break
+			}
+		}
+
+		if got.Err() != nil {
+			t.Errorf("source %q: unexpected error %s", source, got.Err())
+		}
+	}
+}
+
+func TestMultiByteCharacters(t *testing.T) {
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.IDENTIFIER, "caf", 1},
+		{token.ILLEGAL, "é", 4},
+		{token.ASSIGN, "=", 6},
+		{token.ILLEGAL, "€", 8},
+		{token.INT, "5", 9},
+		{token.EOF, "", 10},
+	}
+
+	l := lexer.New("café = €5")
+
+	for i, tt := range tests {
+		tok := l.ReadAndAdvanceToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral || tok.Position.Column != tt.expectedColumn {
+			t.Fatalf("test[%d] - token wrong, expected=%q %q at column %d, got=%q %q at column %d", i, tt.expectedType, tt.expectedLiteral, tt.expectedColumn, tok.Type, tok.Literal, tok.Position.Column)
+		}
+	}
+}
+
+func TestReadError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	l := lexer.NewReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(readErr)))
+
+	var literals []string
+	for tok := range l.Tokens() {
+		literals = append(literals, tok.Literal)
+	}
+
+	if !slices.Equal(literals, []string{"let", "x"}) {
+		t.Errorf("tokens before the error wrong; got %q", literals)
+	}
+
+	if !errors.Is(l.Err(), readErr) {
+		t.Errorf("l.Err() wrong, expected=%v, got=%v", readErr, l.Err())
+	}
+}
+
+func TestTokens(t *testing.T) {
+	l := lexer.New("let x = 5;")
+
+	var types []token.TokenType
+	for tok := range l.Tokens() {
+		types = append(types, tok.Type)
+	}
+
+	expected := []token.TokenType{token.LET, token.IDENTIFIER, token.ASSIGN, token.INT, token.SEMICOLON}
+	if !slices.Equal(types, expected) {
+		t.Errorf("tokens wrong, expected=%q, got=%q", expected, types)
+	}
+
+	// stopping early leaves the rest of the tokens in the lexer
+	l = lexer.New("a b c")
+	for range l.Tokens() {
+		break
+	}
+
+	if tok := l.ReadAndAdvanceToken(); tok.Literal != "b" {
+		t.Errorf("token after break wrong, expected=%q, got=%q", "b", tok.Literal)
+	}
+}
+
+func TestDestructuringTokens(t *testing.T) {
+	source := `let [a, ...rest] = arr;
+let {name, age: years} = person;
+. .. ....`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.LBRACKET, "["},
+		{token.IDENTIFIER, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "rest"},
+		{token.RBRACKET, "]"},
+		{token.ASSIGN, "="},
+		{token.IDENTIFIER, "arr"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "name"},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "age"},
+		{token.COLON, ":"},
+		{token.IDENTIFIER, "years"},
+		{token.RBRACE, "}"},
+		{token.ASSIGN, "="},
+		{token.IDENTIFIER, "person"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, ".."},
+		{token.ELLIPSIS, "..."},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(source)
+
+	for i, tt := range tests {
+		tok := l.ReadAndAdvanceToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	source := `match (x) { 1 => a, n if n == 2 => b, _ => c }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.ARROW, "=>"},
+		{token.IDENTIFIER, "a"},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "n"},
+		{token.IF, "if"},
+		{token.IDENTIFIER, "n"},
+		{token.EQ, "=="},
+		{token.INT, "2"},
+		{token.ARROW, "=>"},
+		{token.IDENTIFIER, "b"},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "_"},
+		{token.ARROW, "=>"},
+		{token.IDENTIFIER, "c"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(source)
+
+	for i, tt := range tests {
+		tok := l.ReadAndAdvanceToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStructTokens(t *testing.T) {
+	source := `struct Point { x, y } Point{x: 1}`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRUCT, "struct"},
+		{token.IDENTIFIER, "Point"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "x"},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "y"},
+		{token.RBRACE, "}"},
+		{token.IDENTIFIER, "Point"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "x"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(source)
+
+	for i, tt := range tests {
+		tok := l.ReadAndAdvanceToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ekediala/interpreter/ast"
+	"github.com/ekediala/interpreter/lexer"
+	"github.com/ekediala/interpreter/parser"
+	"github.com/ekediala/interpreter/token"
+)
+
+// document is an open file along with everything we learnt lexing and parsing it
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+
+	tokens      []token.Token
+	program     *ast.RootNode
+	errors      []parser.ParseError
+	definitions []binding                  // identifiers bound by top-level let statements in the order they appear
+	keys        map[token.Position]bool    // where the keys of hash patterns and the names of structs and their fields are, the age in {age: years} names an entry of the hash rather than a variable
+	locals      map[token.Position]binding // identifiers a match arm binds and their uses in the arm's guard and body, which top-level lets do not reach
+}
+
+// binding is an identifier a let statement or a match arm binds, either as its name or inside the pattern it destructures into. Exactly one of let and arm is set
+type binding struct {
+	let  *ast.LetStatement
+	arm  *ast.MatchArm
+	name *ast.Identifier
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := document{
+		uri:     uri,
+		version: version,
+		text:    text,
+		lines:   strings.Split(text, "\n"),
+		tokens:  slices.Collect(lexer.New(text).Tokens()),
+		keys:    map[token.Position]bool{},
+		locals:  map[token.Position]binding{},
+	}
+
+	p := parser.New(lexer.New(text))
+	d.program = p.ParseProgram()
+	d.errors = p.ParseErrors()
+
+	for _, stmt := range d.program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		for _, name := range ast.BoundIdentifiers(let.Name) {
+			d.definitions = append(d.definitions, binding{let: let, name: name})
+		}
+	}
+
+	// Inspect visits a match before the matches nested in its arms, so the arms of an inner match get the last word on the names they bind
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.HashPattern:
+			for _, pair := range node.Pairs {
+				// the key of the shorthand {name} is also the variable it binds
+				if pair.Key != pair.Value {
+					d.keys[pair.Key.Token.Position] = true
+				}
+			}
+
+		case *ast.StructStatement:
+			d.keys[node.Name.Token.Position] = true
+			for _, field := range node.Fields {
+				d.keys[field.Token.Position] = true
+			}
+
+		case *ast.StructLiteral:
+			d.keys[node.Name.Token.Position] = true
+			for _, field := range node.Fields {
+				d.keys[field.Name.Token.Position] = true
+			}
+
+		case *ast.MatchExpression:
+			for _, arm := range node.Arms {
+				d.bindArm(arm)
+			}
+		}
+		return true
+	})
+
+	return &d
+}
+
+// bindArm records the identifiers the pattern of arm binds, along with every identifier in the guard and body that refers to them
+func (d *document) bindArm(arm *ast.MatchArm) {
+	bound := map[string]binding{}
+	for _, name := range ast.BoundIdentifiers(arm.Pattern) {
+		b := binding{arm: arm, name: name}
+		bound[name.Value] = b
+		d.locals[name.Token.Position] = b
+	}
+
+	if len(bound) == 0 {
+		return
+	}
+
+	for _, exp := range []ast.Expression{arm.Guard, arm.Body} {
+		if exp == nil {
+			continue
+		}
+
+		ast.Inspect(exp, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok {
+				if b, ok := bound[ident.Value]; ok {
+					d.locals[ident.Token.Position] = b
+				}
+			}
+			return true
+		})
+	}
+}
+
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0, len(d.errors))
+	for _, err := range d.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.errorRange(err.Position),
+			Severity: severityError,
+			Source:   "interpreter",
+			Message:  err.Message,
+		})
+	}
+	return diagnostics
+}
+
+// errorRange covers the token the error points at, or nothing when it points past the last token
+func (d *document) errorRange(position token.Position) Range {
+	for _, tok := range d.tokens {
+		if tok.Position == position {
+			return d.tokenRange(tok)
+		}
+	}
+
+	start := d.toProtocol(position)
+	return Range{Start: start, End: start}
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	end := tok.Position
+	end.Column += utf8.RuneCountInString(tok.Literal)
+	return Range{Start: d.toProtocol(tok.Position), End: d.toProtocol(end)}
+}
+
+// toProtocol turns a lexer position, 1-based and counting characters, into an LSP position, 0-based and counting UTF-16 code units
+func (d *document) toProtocol(position token.Position) Position {
+	line := position.Line - 1
+	if line < 0 || line >= len(d.lines) {
+		return Position{Line: max(line, 0)}
+	}
+
+	character := 0
+	column := 1
+	for _, ch := range d.lines[line] {
+		if column >= position.Column {
+			break
+		}
+		character += utf16.RuneLen(ch)
+		column++
+	}
+
+	return Position{Line: line, Character: character + position.Column - column}
+}
+
+// fromProtocol is the inverse of toProtocol
+func (d *document) fromProtocol(position Position) token.Position {
+	if position.Line < 0 || position.Line >= len(d.lines) {
+		return token.Position{Line: position.Line + 1, Column: position.Character + 1}
+	}
+
+	character := 0
+	column := 1
+	for _, ch := range d.lines[position.Line] {
+		if character >= position.Character {
+			break
+		}
+		character += utf16.RuneLen(ch)
+		column++
+	}
+
+	// a position past the end of the line stays past it
+	column += max(position.Character-character, 0)
+
+	return token.Position{Line: position.Line + 1, Column: column}
+}
+
+// tokenAt finds the token under the cursor. A cursor right after the last character of a token still counts as on it, the way editors place it after typing a name
+func (d *document) tokenAt(position Position) (token.Token, bool) {
+	at := d.fromProtocol(position)
+
+	var found token.Token
+	ok := false
+	for _, tok := range d.tokens {
+		if tok.Position.Line != at.Line {
+			continue
+		}
+
+		start := tok.Position.Column
+		end := start + utf8.RuneCountInString(tok.Literal)
+		if at.Column < start || at.Column > end {
+			continue
+		}
+
+		// prefer a token that starts under the cursor over one that ends there
+		if at.Column < end || !ok {
+			found, ok = tok, true
+		}
+	}
+
+	return found, ok
+}
+
+// definition returns the binding of tok: the match arm it is bound by, otherwise the last let before tok, or the first one when tok comes before all of them
+func (d *document) definition(tok token.Token) (binding, bool) {
+	if tok.Type != token.IDENTIFIER || d.keys[tok.Position] {
+		return binding{}, false
+	}
+
+	if b, ok := d.locals[tok.Position]; ok {
+		return b, true
+	}
+
+	var found binding
+	ok := false
+	for _, b := range d.definitions {
+		if b.name.Value != tok.Literal {
+			continue
+		}
+
+		if !ok || before(b.name.Token.Position, tok.Position) || b.name.Token.Position == tok.Position {
+			found, ok = b, true
+		}
+	}
+
+	return found, ok
+}
+
+// references returns every identifier token bound by the same let or match arm as tok. Lets bind names for the rest of the document, so for them we go through the tokens rather than the tree
+func (d *document) references(tok token.Token, includeDeclaration bool) []token.Token {
+	b, ok := d.definition(tok)
+	if !ok {
+		return nil
+	}
+
+	var references []token.Token
+	for _, candidate := range d.tokens {
+		if candidate.Type != token.IDENTIFIER || candidate.Literal != tok.Literal || d.keys[candidate.Position] {
+			continue
+		}
+
+		// a name a match arm binds is a different variable from the let of the same name, and from the one another arm binds
+		local, isLocal := d.locals[candidate.Position]
+		if isLocal != (b.arm != nil) || isLocal && local.name != b.name {
+			continue
+		}
+
+		if !includeDeclaration && d.isDeclaration(candidate) {
+			continue
+		}
+
+		references = append(references, candidate)
+	}
+
+	return references
+}
+
+func (d *document) isDeclaration(tok token.Token) bool {
+	if b, ok := d.locals[tok.Position]; ok {
+		return b.name.Token.Position == tok.Position
+	}
+
+	for _, b := range d.definitions {
+		if b.name.Token.Position == tok.Position {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeAt returns the innermost node whose token is tok
+func (d *document) nodeAt(tok token.Token) (ast.Node, bool) {
+	var found ast.Node
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		if node == nil {
+			return false
+		}
+
+		if nodeToken, ok := tokenOf(node); ok && nodeToken.Position == tok.Position {
+			found = node
+		}
+		return true
+	})
+
+	return found, found != nil
+}
+
+func (d *document) hover(tok token.Token) string {
+	node, ok := d.nodeAt(tok)
+	if !ok {
+		return fmt.Sprintf("**%s** `%s`", tok.Type, tok.Literal)
+	}
+
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+
+	var value string
+	switch node := node.(type) {
+	case *ast.Identifier:
+		value = node.Value
+	case *ast.IntegerLiteral:
+		value = fmt.Sprint(node.Value)
+	case *ast.Boolean:
+		value = fmt.Sprint(node.Value)
+	case *ast.PrefixExpression:
+		value = node.Operator
+	case *ast.InfixExpression:
+		value = node.Operator
+	case *ast.LetStatement:
+		value = node.Name.String()
+	case *ast.StructStatement:
+		value = node.Name.Value
+	case *ast.ArrayPattern, *ast.HashPattern:
+		value = node.String()
+	default:
+		value = tok.Literal
+	}
+
+	contents := fmt.Sprintf("**%s** `%s`", kind, value)
+	if b, ok := d.definition(tok); ok {
+		if b.arm != nil {
+			contents += fmt.Sprintf("\n\nbound by the match arm pattern at %s", b.name.Token.Position)
+		} else {
+			contents += fmt.Sprintf("\n\nbound by the let statement at %s", b.let.Token.Position)
+		}
+	}
+
+	return contents
+}
+
+func (d *document) symbols() []DocumentSymbol {
+	symbols := make([]DocumentSymbol, 0, len(d.definitions))
+	for _, b := range d.definitions {
+		name := d.tokenRange(b.name.Token)
+		symbols = append(symbols, DocumentSymbol{
+			Name:           b.name.Value,
+			Kind:           symbolKindVariable,
+			Range:          Range{Start: d.toProtocol(b.let.Token.Position), End: name.End},
+			SelectionRange: name,
+		})
+	}
+	return symbols
+}
+
+// the semantic token types and modifiers we report, in the order of the legend sent to the client
+var (
+	semanticTokenTypes     = []string{"keyword", "variable", "number", "operator"}
+	semanticTokenModifiers = []string{"declaration"}
+)
+
+const (
+	semanticKeyword = iota
+	semanticVariable
+	semanticNumber
+	semanticOperator
+)
+
+const semanticDeclaration = 1 << 0
+
+// semanticTokens encodes the tokens as the protocol wants them: five integers per token, the line and start relative to the previous token, the length, the type and the modifiers
+func (d *document) semanticTokens() []int {
+	data := []int{}
+	previous := Position{}
+
+	for _, tok := range d.tokens {
+		tokenType, ok := semanticTypeOf(tok.Type)
+		if !ok {
+			continue
+		}
+
+		modifiers := 0
+		if tok.Type == token.IDENTIFIER && d.isDeclaration(tok) {
+			modifiers |= semanticDeclaration
+		}
+
+		r := d.tokenRange(tok)
+		deltaLine := r.Start.Line - previous.Line
+		deltaStart := r.Start.Character
+		if deltaLine == 0 {
+			deltaStart -= previous.Character
+		}
+
+		data = append(data, deltaLine, deltaStart, r.End.Character-r.Start.Character, tokenType, modifiers)
+		previous = r.Start
+	}
+
+	return data
+}
+
+func semanticTypeOf(t token.TokenType) (int, bool) {
+	switch t {
+	case token.LET, token.FUNCTION, token.IF, token.ELSE, token.RETURN, token.MATCH, token.STRUCT, token.TRUE, token.FALSE:
+		return semanticKeyword, true
+	case token.IDENTIFIER:
+		return semanticVariable, true
+	case token.INT:
+		return semanticNumber, true
+	case token.ELLIPSIS, token.ARROW, token.ASSIGN, token.PLUS, token.MINUS, token.BANG, token.ASTERISK, token.SLASH, token.LT, token.GT, token.EQ, token.NOT_EQ:
+		return semanticOperator, true
+	}
+	return 0, false
+}
+
+// end is the position just past the last character of the document
+func (d *document) end() Position {
+	last := len(d.lines) - 1
+	return Position{Line: last, Character: len(utf16.Encode([]rune(d.lines[last])))}
+}
+
+func tokenOf(node ast.Node) (token.Token, bool) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token, true
+	case *ast.ReturnStatement:
+		return node.Token, true
+	case *ast.ExpressionStatement:
+		return node.Token, true
+	case *ast.PrefixExpression:
+		return node.Token, true
+	case *ast.InfixExpression:
+		return node.Token, true
+	case *ast.MatchExpression:
+		return node.Token, true
+	case *ast.StructStatement:
+		return node.Token, true
+	case *ast.StructLiteral:
+		return node.Token, true
+	case *ast.ArrayPattern:
+		return node.Token, true
+	case *ast.HashPattern:
+		return node.Token, true
+	case *ast.Wildcard:
+		return node.Token, true
+	case *ast.Identifier:
+		return node.Token, true
+	case *ast.IntegerLiteral:
+		return node.Token, true
+	case *ast.Boolean:
+		return node.Token, true
+	}
+	return token.Token{}, false
+}
+
+func before(a, b token.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
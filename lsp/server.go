@@ -0,0 +1,280 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ekediala/interpreter/format"
+)
+
+// Server answers LSP requests for the documents an editor opens. Requests are handled one at a time in the order they arrive
+type Server struct {
+	conn      *conn
+	documents map[string]*document
+
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer reads messages from in and writes responses and notifications to out, usually stdin and stdout of the process the editor started
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		conn:      newConn(in, out),
+		documents: map[string]*document{},
+	}
+}
+
+// ErrExitWithoutShutdown is returned by Serve when the client sends exit before shutdown. The process should then exit with code 1
+var ErrExitWithoutShutdown = errors.New("exit received before shutdown")
+
+// Serve handles messages until the client sends exit or in is closed
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var respErr *ResponseError
+		if errors.As(err, &respErr) {
+			// the message was framed correctly but its body is not JSON, tell the client and carry on
+			if err := s.conn.reply(json.RawMessage("null"), nil, respErr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) error {
+	isRequest := msg.ID != nil
+
+	if !isRequest {
+		return s.handleNotification(msg)
+	}
+
+	result, respErr := s.handleRequest(msg)
+	return s.conn.reply(msg.ID, result, respErr)
+}
+
+func (s *Server) handleRequest(msg *message) (any, *ResponseError) {
+	if msg.Method != "initialize" && !s.initialized {
+		return nil, &ResponseError{Code: codeServerNotInitialized, Message: "initialize has not been sent"}
+	}
+
+	if s.shutdown {
+		return nil, &ResponseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize()
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		d, respErr := s.decode(msg.Params, &params, &params.TextDocument)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return s.hover(d, params.Position), nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		d, respErr := s.decode(msg.Params, &params, &params.TextDocument)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return s.definition(d, params.Position), nil
+
+	case "textDocument/references":
+		var params ReferenceParams
+		d, respErr := s.decode(msg.Params, &params, &params.TextDocument)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return s.references(d, params.Position, params.Context.IncludeDeclaration), nil
+
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		d, respErr := s.decode(msg.Params, &params, &params.TextDocument)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return d.symbols(), nil
+
+	case "textDocument/semanticTokens/full":
+		var params SemanticTokensParams
+		d, respErr := s.decode(msg.Params, &params, &params.TextDocument)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return SemanticTokens{Data: d.semanticTokens()}, nil
+
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		d, respErr := s.decode(msg.Params, &params, &params.TextDocument)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return s.formatting(d)
+	}
+
+	return nil, &ResponseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// decode unmarshals raw into params and looks up the document params refers to through textDocument, which must point into params
+func (s *Server) decode(raw json.RawMessage, params any, textDocument *TextDocumentIdentifier) (*document, *ResponseError) {
+	if err := json.Unmarshal(raw, params); err != nil {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	d, ok := s.documents[textDocument.URI]
+	if !ok {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %q is not open", textDocument.URI)}
+	}
+
+	return d, nil
+}
+
+func (s *Server) handleNotification(msg *message) error {
+	// notifications get no response, so there is nobody to tell about params we cannot decode. drop them like the ones we do not support
+	switch msg.Method {
+	case "initialized":
+		// nothing to do, we registered every capability in initialize
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		item := params.TextDocument
+		return s.open(newDocument(item.URI, item.Version, item.Text))
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// we asked for full sync, so the last change holds the whole document
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.open(newDocument(params.TextDocument.URI, params.TextDocument.Version, text))
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		// clear the diagnostics of the closed document from the editor
+		return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	}
+
+	return nil
+}
+
+func (s *Server) open(d *document) error {
+	s.documents[d.uri] = d
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: d.diagnostics(),
+	})
+}
+
+func (s *Server) initialize() (any, *ResponseError) {
+	s.initialized = true
+
+	return InitializeResult{
+		ServerInfo: ServerInfo{Name: "interpreter"},
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           textDocumentSyncFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+			SemanticTokensProvider: &SemanticTokensOptions{
+				Legend: SemanticTokensLegend{TokenTypes: semanticTokenTypes, TokenModifiers: semanticTokenModifiers},
+				Full:   true,
+			},
+		},
+	}, nil
+}
+
+// hover returns nil, which the protocol reads as nothing to show, when the cursor is not on a token
+func (s *Server) hover(d *document, position Position) *Hover {
+	tok, ok := d.tokenAt(position)
+	if !ok {
+		return nil
+	}
+
+	r := d.tokenRange(tok)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: d.hover(tok)},
+		Range:    &r,
+	}
+}
+
+func (s *Server) definition(d *document, position Position) *Location {
+	tok, ok := d.tokenAt(position)
+	if !ok {
+		return nil
+	}
+
+	b, ok := d.definition(tok)
+	if !ok {
+		return nil
+	}
+
+	return &Location{URI: d.uri, Range: d.tokenRange(b.name.Token)}
+}
+
+func (s *Server) references(d *document, position Position, includeDeclaration bool) []Location {
+	locations := []Location{}
+
+	tok, ok := d.tokenAt(position)
+	if !ok {
+		return locations
+	}
+
+	for _, reference := range d.references(tok, includeDeclaration) {
+		locations = append(locations, Location{URI: d.uri, Range: d.tokenRange(reference)})
+	}
+
+	return locations
+}
+
+// formatting replaces the whole document with its formatted source, or changes nothing when it is already formatted
+func (s *Server) formatting(d *document) ([]TextEdit, *ResponseError) {
+	formatted, err := format.Source(d.text)
+	if err != nil {
+		return nil, &ResponseError{Code: codeInternalError, Message: err.Error()}
+	}
+
+	if formatted == d.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{Range: Range{End: d.end()}, NewText: formatted}}, nil
+}
@@ -0,0 +1,666 @@
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ekediala/interpreter/lsp"
+)
+
+// client is the editor side of the connection. It runs the server in the same process and talks to it over pipes the way an editor would over stdio
+type client struct {
+	t        *testing.T
+	out      io.WriteCloser
+	messages chan rawMessage
+	served   chan error
+	nextID   int
+}
+
+type rawMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := client{
+		t:        t,
+		out:      clientOut,
+		messages: make(chan rawMessage, 100),
+		served:   make(chan error, 1),
+	}
+
+	go func() {
+		c.served <- lsp.NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+
+	go func() {
+		defer close(c.messages)
+		in := textproto.NewReader(bufio.NewReader(clientIn))
+		for {
+			header, err := in.ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			body := make([]byte, length)
+			if _, err := io.ReadFull(in.R, body); err != nil {
+				return
+			}
+			var msg rawMessage
+			if err := json.Unmarshal(body, &msg); err != nil {
+				t.Errorf("server sent invalid JSON %q: %s", body, err)
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+
+	t.Cleanup(func() { clientOut.Close() })
+
+	return &c
+}
+
+func (c *client) send(msg map[string]any) {
+	c.t.Helper()
+
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if _, err := fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	c.send(map[string]any{"method": method, "params": params})
+}
+
+// call sends a request and waits for its response, decoding the result into result
+func (c *client) call(method string, params any, result any) error {
+	c.t.Helper()
+
+	c.nextID++
+	id := c.nextID
+	c.send(map[string]any{"id": id, "method": method, "params": params})
+
+	for {
+		msg := c.receive()
+		if msg.ID == nil || *msg.ID != id || msg.Method != "" {
+			continue
+		}
+
+		if msg.Error != nil {
+			return &lsp.ResponseError{Code: msg.Error.Code, Message: msg.Error.Message}
+		}
+
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatalf("could not decode result of %s %s: %s", method, msg.Result, err)
+			}
+		}
+		return nil
+	}
+}
+
+// diagnostics waits for the next diagnostics the server publishes
+func (c *client) diagnostics() lsp.PublishDiagnosticsParams {
+	c.t.Helper()
+
+	for {
+		msg := c.receive()
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var params lsp.PublishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		return params
+	}
+}
+
+func (c *client) receive() rawMessage {
+	c.t.Helper()
+
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("server closed the connection")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+	return rawMessage{}
+}
+
+const uri = "file:///test.jp"
+
+// start initializes the server and opens a document holding text
+func start(t *testing.T, text string) *client {
+	t.Helper()
+
+	c := newClient(t)
+	if err := c.call("initialize", map[string]any{"capabilities": map[string]any{}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.notify("initialized", map[string]any{})
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, LanguageID: "jpops", Version: 1, Text: text},
+	})
+	c.diagnostics()
+
+	return c
+}
+
+func position(line, character int) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     lsp.Position{Line: line, Character: character},
+	}
+}
+
+func span(line, start, end int) lsp.Range {
+	return lsp.Range{Start: lsp.Position{Line: line, Character: start}, End: lsp.Position{Line: line, Character: end}}
+}
+
+func TestInitialize(t *testing.T) {
+	c := newClient(t)
+
+	var result lsp.InitializeResult
+	if err := c.call("initialize", map[string]any{}, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	capabilities := result.Capabilities
+	if capabilities.TextDocumentSync != 1 || !capabilities.HoverProvider || !capabilities.DefinitionProvider ||
+		!capabilities.ReferencesProvider || !capabilities.DocumentSymbolProvider || !capabilities.DocumentFormattingProvider {
+		t.Errorf("capabilities wrong; got %+v", capabilities)
+	}
+
+	if capabilities.SemanticTokensProvider == nil || !capabilities.SemanticTokensProvider.Full {
+		t.Fatalf("semantic tokens not advertised; got %+v", capabilities.SemanticTokensProvider)
+	}
+
+	expectedTypes := []string{"keyword", "variable", "number", "operator"}
+	if !reflect.DeepEqual(capabilities.SemanticTokensProvider.Legend.TokenTypes, expectedTypes) {
+		t.Errorf("token types wrong; expected %v, got %v", expectedTypes, capabilities.SemanticTokensProvider.Legend.TokenTypes)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	c := newClient(t)
+
+	err := c.call("textDocument/hover", position(0, 0), nil)
+	var respErr *lsp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Code != -32002 {
+		t.Errorf("request before initialize should fail with -32002; got %v", err)
+	}
+
+	c.call("initialize", map[string]any{}, nil)
+
+	err = c.call("workspace/symbol", map[string]any{}, nil)
+	if !errors.As(err, &respErr) || respErr.Code != -32601 {
+		t.Errorf("unknown method should fail with -32601; got %v", err)
+	}
+
+	err = c.call("textDocument/hover", position(0, 0), nil)
+	if !errors.As(err, &respErr) || respErr.Code != -32602 {
+		t.Errorf("request for a document that is not open should fail with -32602; got %v", err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	c.call("initialize", map[string]any{}, nil)
+
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, Version: 1, Text: "let x 5;\n1 +"},
+	})
+
+	published := c.diagnostics()
+	if published.URI != uri || published.Version != 1 {
+		t.Errorf("diagnostics for the wrong document; got %s version %d", published.URI, published.Version)
+	}
+
+	expected := []lsp.Diagnostic{
+		{Range: span(0, 6, 7), Severity: 1, Source: "interpreter", Message: `expected next token to be "=", got INT instead`},
+		{Range: span(1, 3, 3), Severity: 1, Source: "interpreter", Message: "no prefix parse function for EOF found"},
+	}
+	if !reflect.DeepEqual(published.Diagnostics, expected) {
+		t.Errorf("diagnostics wrong;\nexpected %+v\ngot      %+v", expected, published.Diagnostics)
+	}
+
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "let x = 5;"}},
+	})
+
+	published = c.diagnostics()
+	if published.Version != 2 || len(published.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics for version 2; got %+v", published)
+	}
+
+	c.notify("textDocument/didClose", lsp.DidCloseTextDocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}})
+
+	published = c.diagnostics()
+	if len(published.Diagnostics) != 0 {
+		t.Errorf("closing a document should clear its diagnostics; got %+v", published.Diagnostics)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := start(t, "let five = 5;\nfive + 10;\n-five")
+
+	tests := []struct {
+		line, character int
+		expected        string
+	}{
+		{1, 0, "**Identifier** `five`\n\nbound by the let statement at 1:1"},
+		{1, 4, "**Identifier** `five`\n\nbound by the let statement at 1:1"},
+		{1, 5, "**InfixExpression** `+`"},
+		{1, 8, "**IntegerLiteral** `10`"},
+		{0, 1, "**LetStatement** `five`"},
+		{2, 0, "**PrefixExpression** `-`"},
+		{0, 11, "**IntegerLiteral** `5`"},
+		// no node has the = as its token, so all we know about it is the token
+		{0, 9, "**=** `=`"},
+	}
+
+	for _, tt := range tests {
+		var hover *lsp.Hover
+		if err := c.call("textDocument/hover", position(tt.line, tt.character), &hover); err != nil {
+			t.Fatal(err)
+		}
+
+		if hover == nil {
+			t.Errorf("no hover at %d:%d", tt.line, tt.character)
+			continue
+		}
+
+		if hover.Contents.Value != tt.expected {
+			t.Errorf("hover at %d:%d wrong; expected %q, got %q", tt.line, tt.character, tt.expected, hover.Contents.Value)
+		}
+	}
+
+	var hover *lsp.Hover
+	if err := c.call("textDocument/hover", position(0, 20), &hover); err != nil {
+		t.Fatal(err)
+	}
+	if hover != nil {
+		t.Errorf("expected no hover past the end of the line; got %+v", hover)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := start(t, "x;\nlet x = 5;\nx + y;\nlet x = 10;\nx;")
+
+	tests := []struct {
+		line, character int
+		expected        *lsp.Location
+	}{
+		// used before any let, jump to the first
+		{0, 0, &lsp.Location{URI: uri, Range: span(1, 4, 5)}},
+		{2, 0, &lsp.Location{URI: uri, Range: span(1, 4, 5)}},
+		// shadowed by the second let
+		{4, 0, &lsp.Location{URI: uri, Range: span(3, 4, 5)}},
+		{3, 4, &lsp.Location{URI: uri, Range: span(3, 4, 5)}},
+		// y is never bound
+		{2, 4, nil},
+		{1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		var location *lsp.Location
+		if err := c.call("textDocument/definition", position(tt.line, tt.character), &location); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(location, tt.expected) {
+			t.Errorf("definition at %d:%d wrong; expected %+v, got %+v", tt.line, tt.character, tt.expected, location)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	c := start(t, "let total = 5;\ntotal + other;\n!total")
+
+	references := func(includeDeclaration bool) []lsp.Location {
+		params := lsp.ReferenceParams{
+			TextDocumentPositionParams: position(1, 2),
+			Context:                    lsp.ReferenceContext{IncludeDeclaration: includeDeclaration},
+		}
+
+		var locations []lsp.Location
+		if err := c.call("textDocument/references", params, &locations); err != nil {
+			t.Fatal(err)
+		}
+		return locations
+	}
+
+	expected := []lsp.Location{{URI: uri, Range: span(0, 4, 9)}, {URI: uri, Range: span(1, 0, 5)}, {URI: uri, Range: span(2, 1, 6)}}
+	if got := references(true); !reflect.DeepEqual(got, expected) {
+		t.Errorf("references wrong;\nexpected %+v\ngot      %+v", expected, got)
+	}
+
+	if got := references(false); !reflect.DeepEqual(got, expected[1:]) {
+		t.Errorf("references without declaration wrong;\nexpected %+v\ngot      %+v", expected[1:], got)
+	}
+
+	var locations []lsp.Location
+	params := lsp.ReferenceParams{TextDocumentPositionParams: position(1, 9)}
+	if err := c.call("textDocument/references", params, &locations); err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 0 {
+		t.Errorf("an identifier no let binds has no references; got %+v", locations)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := start(t, "let x = 5;\n  let total = x;\nx;")
+
+	var symbols []lsp.DocumentSymbol
+	params := lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}}
+	if err := c.call("textDocument/documentSymbol", params, &symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []lsp.DocumentSymbol{
+		{Name: "x", Kind: 13, Range: span(0, 0, 5), SelectionRange: span(0, 4, 5)},
+		{Name: "total", Kind: 13, Range: span(1, 2, 11), SelectionRange: span(1, 6, 11)},
+	}
+
+	if !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("symbols wrong;\nexpected %+v\ngot      %+v", expected, symbols)
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	c := start(t, "let x = 5;\n  x == true;")
+
+	var tokens lsp.SemanticTokens
+	params := lsp.SemanticTokensParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}}
+	if err := c.call("textDocument/semanticTokens/full", params, &tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{
+		0, 0, 3, 0, 0, // let
+		0, 4, 1, 1, 1, // x, declared
+		0, 2, 1, 3, 0, // =
+		0, 2, 1, 2, 0, // 5
+		1, 2, 1, 1, 0, // x
+		0, 2, 2, 3, 0, // ==
+		0, 3, 4, 0, 0, // true
+	}
+
+	if !reflect.DeepEqual(tokens.Data, expected) {
+		t.Errorf("semantic tokens wrong;\nexpected %v\ngot      %v", expected, tokens.Data)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := start(t, "let x=5;\nif(x){x+1;}")
+
+	var edits []lsp.TextEdit
+	params := lsp.DocumentFormattingParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}}
+	if err := c.call("textDocument/formatting", params, &edits); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []lsp.TextEdit{{Range: lsp.Range{End: lsp.Position{Line: 1, Character: 11}}, NewText: "let x = 5;\nif (x) {\n\tx + 1;\n}\n"}}
+	if !reflect.DeepEqual(edits, expected) {
+		t.Errorf("edits wrong;\nexpected %+v\ngot      %+v", expected, edits)
+	}
+
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: expected[0].NewText}},
+	})
+	c.diagnostics()
+
+	if err := c.call("textDocument/formatting", params, &edits); err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 0 {
+		t.Errorf("a formatted document needs no edits; got %+v", edits)
+	}
+}
+
+func TestShutdownAndExit(t *testing.T) {
+	c := newClient(t)
+	c.call("initialize", map[string]any{}, nil)
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.notify("exit", nil)
+
+	select {
+	case err := <-c.served:
+		if err != nil {
+			t.Errorf("Serve() should return nil after shutdown and exit; got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not exit")
+	}
+
+	c = newClient(t)
+	c.notify("exit", nil)
+
+	select {
+	case err := <-c.served:
+		if !errors.Is(err, lsp.ErrExitWithoutShutdown) {
+			t.Errorf("Serve() should return ErrExitWithoutShutdown; got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not exit")
+	}
+}
+
+func TestUTF16Positions(t *testing.T) {
+	// 𝄞 takes two UTF-16 code units but is a single character to the lexer
+	c := start(t, "𝄞; let x = 1; x")
+
+	var location *lsp.Location
+	if err := c.call("textDocument/definition", position(0, 15), &location); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &lsp.Location{URI: uri, Range: span(0, 8, 9)}
+	if !reflect.DeepEqual(location, expected) {
+		t.Errorf("definition wrong; expected %+v, got %+v", expected, location)
+	}
+}
+
+func TestDestructuredDefinitions(t *testing.T) {
+	c := start(t, "let age = 1;\nlet [first, ...others] = list;\nlet {name, age: years} = person;\nfirst + years + age;")
+
+	tests := []struct {
+		line, character int
+		expected        *lsp.Location
+	}{
+		{3, 0, &lsp.Location{URI: uri, Range: span(1, 5, 10)}},
+		{3, 8, &lsp.Location{URI: uri, Range: span(2, 16, 21)}},
+		// age in the pattern is a key of the hash, not the age the first let binds
+		{2, 11, nil},
+		{3, 16, &lsp.Location{URI: uri, Range: span(0, 4, 7)}},
+	}
+
+	for _, tt := range tests {
+		var location *lsp.Location
+		if err := c.call("textDocument/definition", position(tt.line, tt.character), &location); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(location, tt.expected) {
+			t.Errorf("definition at %d:%d wrong; expected %+v, got %+v", tt.line, tt.character, tt.expected, location)
+		}
+	}
+
+	var symbols []lsp.DocumentSymbol
+	params := lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}}
+	if err := c.call("textDocument/documentSymbol", params, &symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, symbol := range symbols {
+		names = append(names, symbol.Name)
+	}
+
+	expected := []string{"age", "first", "others", "name", "years"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("symbols wrong; expected %v, got %v", expected, names)
+	}
+
+	var locations []lsp.Location
+	references := lsp.ReferenceParams{TextDocumentPositionParams: position(3, 16), Context: lsp.ReferenceContext{IncludeDeclaration: true}}
+	if err := c.call("textDocument/references", references, &locations); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLocations := []lsp.Location{{URI: uri, Range: span(0, 4, 7)}, {URI: uri, Range: span(3, 16, 19)}}
+	if !reflect.DeepEqual(locations, expectedLocations) {
+		t.Errorf("references of age wrong;\nexpected %+v\ngot      %+v", expectedLocations, locations)
+	}
+
+	var hover *lsp.Hover
+	if err := c.call("textDocument/hover", position(2, 11), &hover); err != nil {
+		t.Fatal(err)
+	}
+	if hover == nil || hover.Contents.Value != "**Identifier** `age`" {
+		t.Errorf("hover over the key age wrong; got %+v", hover)
+	}
+}
+
+func TestMatchArmBindings(t *testing.T) {
+	c := start(t, "let n = 1;\nmatch (x) { n => n, _ => 0 };\nn;")
+
+	tests := []struct {
+		line, character int
+		expected        *lsp.Location
+	}{
+		// the n of the arm is its own variable, not the one the let binds
+		{1, 17, &lsp.Location{URI: uri, Range: span(1, 12, 13)}},
+		{1, 12, &lsp.Location{URI: uri, Range: span(1, 12, 13)}},
+		{2, 0, &lsp.Location{URI: uri, Range: span(0, 4, 5)}},
+	}
+
+	for _, tt := range tests {
+		var location *lsp.Location
+		if err := c.call("textDocument/definition", position(tt.line, tt.character), &location); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(location, tt.expected) {
+			t.Errorf("definition at %d:%d wrong; expected %+v, got %+v", tt.line, tt.character, tt.expected, location)
+		}
+	}
+
+	references := func(line, character int) []lsp.Location {
+		params := lsp.ReferenceParams{
+			TextDocumentPositionParams: position(line, character),
+			Context:                    lsp.ReferenceContext{IncludeDeclaration: true},
+		}
+
+		var locations []lsp.Location
+		if err := c.call("textDocument/references", params, &locations); err != nil {
+			t.Fatal(err)
+		}
+		return locations
+	}
+
+	expected := []lsp.Location{{URI: uri, Range: span(0, 4, 5)}, {URI: uri, Range: span(2, 0, 1)}}
+	if got := references(0, 4); !reflect.DeepEqual(got, expected) {
+		t.Errorf("references of the let wrong;\nexpected %+v\ngot      %+v", expected, got)
+	}
+
+	expected = []lsp.Location{{URI: uri, Range: span(1, 12, 13)}, {URI: uri, Range: span(1, 17, 18)}}
+	if got := references(1, 17); !reflect.DeepEqual(got, expected) {
+		t.Errorf("references of the arm wrong;\nexpected %+v\ngot      %+v", expected, got)
+	}
+
+	var hover *lsp.Hover
+	if err := c.call("textDocument/hover", position(1, 17), &hover); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := "**Identifier** `n`\n\nbound by the match arm pattern at 2:13"
+	if hover == nil || hover.Contents.Value != contents {
+		t.Errorf("hover over the n of the arm wrong; expected %q, got %+v", contents, hover)
+	}
+}
+
+func TestStructNamesAreNotVariables(t *testing.T) {
+	c := start(t, "let x = 1;\nstruct Point { x, y }\nPoint{x: x, y: 2};")
+
+	tests := []struct {
+		line, character int
+		expected        *lsp.Location
+	}{
+		// the field names and the name of the struct are not the x the let binds
+		{1, 15, nil},
+		{2, 6, nil},
+		{2, 0, nil},
+		{2, 9, &lsp.Location{URI: uri, Range: span(0, 4, 5)}},
+	}
+
+	for _, tt := range tests {
+		var location *lsp.Location
+		if err := c.call("textDocument/definition", position(tt.line, tt.character), &location); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(location, tt.expected) {
+			t.Errorf("definition at %d:%d wrong; expected %+v, got %+v", tt.line, tt.character, tt.expected, location)
+		}
+	}
+
+	var locations []lsp.Location
+	references := lsp.ReferenceParams{TextDocumentPositionParams: position(0, 4), Context: lsp.ReferenceContext{IncludeDeclaration: true}}
+	if err := c.call("textDocument/references", references, &locations); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []lsp.Location{{URI: uri, Range: span(0, 4, 5)}, {URI: uri, Range: span(2, 9, 10)}}
+	if !reflect.DeepEqual(locations, expected) {
+		t.Errorf("references of x wrong;\nexpected %+v\ngot      %+v", expected, locations)
+	}
+
+	var hover *lsp.Hover
+	if err := c.call("textDocument/hover", position(1, 0), &hover); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := "**StructStatement** `Point`"
+	if hover == nil || hover.Contents.Value != contents {
+		t.Errorf("hover over struct wrong; expected %q, got %+v", contents, hover)
+	}
+}
@@ -1,20 +1,66 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/user"
+	"strings"
 
+	"github.com/ekediala/interpreter/optimizer"
 	"github.com/ekediala/interpreter/repl"
 )
 
 func main() {
+	dumpOptimized := flag.Bool("dump-optimized", false, "print the optimized tree of every line instead of its tokens")
+	passes := flag.String("passes", "", "comma separated optimizer passes to run with -dump-optimized; runs every pass when empty")
+	flag.Parse()
+
+	// the passes only run for the dump, do not let them be silently ignored
+	if *passes != "" && !*dumpOptimized {
+		log.Fatal("-passes can only be used together with -dump-optimized")
+	}
+
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Arg(0), flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var opts []repl.Option
+	if *dumpOptimized {
+		selected, err := lookupPasses(*passes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, repl.WithOptimizedDump(selected...))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("Hello %s! This is the JPops Programming language!\n", user.Username)
 	fmt.Println("Feel free to type in commands")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, opts...)
+}
+
+func lookupPasses(names string) ([]optimizer.Pass, error) {
+	if names == "" {
+		return nil, nil
+	}
+
+	var passes []optimizer.Pass
+	for _, name := range strings.Split(names, ",") {
+		pass, ok := optimizer.Lookup(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown optimizer pass %q", name)
+		}
+		passes = append(passes, pass)
+	}
+
+	return passes, nil
 }
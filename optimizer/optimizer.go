@@ -0,0 +1,207 @@
+package optimizer
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/ekediala/interpreter/ast"
+	"github.com/ekediala/interpreter/token"
+)
+
+// A Pass rewrites the program it is given in place. Passes are run in the order they are handed to Optimize
+type Pass struct {
+	Name string
+	Run  func(program *ast.RootNode)
+}
+
+var (
+	// ConstantFolding replaces prefix and infix expressions whose operands are literals with the literal they evaluate to
+	ConstantFolding = Pass{Name: "constant-folding", Run: foldConstants}
+
+	// UnreachableCode drops every statement that follows a return statement in the same block
+	UnreachableCode = Pass{Name: "unreachable-code", Run: removeUnreachableCode}
+)
+
+// Passes returns every available pass in the order they should run
+func Passes() []Pass {
+	return []Pass{ConstantFolding, UnreachableCode}
+}
+
+// Lookup finds a pass by its name
+func Lookup(name string) (Pass, bool) {
+	for _, pass := range Passes() {
+		if pass.Name == name {
+			return pass, true
+		}
+	}
+
+	return Pass{}, false
+}
+
+// Optimize runs passes over program and returns it. When no pass is given every available pass is run
+func Optimize(program *ast.RootNode, passes ...Pass) *ast.RootNode {
+	if len(passes) == 0 {
+		passes = Passes()
+	}
+
+	for _, pass := range passes {
+		pass.Run(program)
+	}
+
+	return program
+}
+
+func foldConstants(program *ast.RootNode) {
+	// ast.Modify hands us the children of an expression before the expression itself so nested expressions like 2 * 3 + 4 collapse completely
+	ast.Modify(program, fold)
+}
+
+func fold(node ast.Node) ast.Node {
+	var folded ast.Expression
+
+	switch exp := node.(type) {
+	case *ast.PrefixExpression:
+		folded = foldPrefix(exp)
+	case *ast.InfixExpression:
+		folded = foldInfix(exp)
+	}
+
+	if folded == nil {
+		return node
+	}
+
+	return folded
+}
+
+func foldPrefix(exp *ast.PrefixExpression) ast.Expression {
+	switch right := exp.Right.(type) {
+	case *ast.IntegerLiteral:
+		if exp.Operator == "-" {
+			// -x overflows for the smallest int64, which has no positive counterpart
+			if right.Value == math.MinInt64 {
+				return nil
+			}
+			return foldedInteger(-right.Value, exp.Token.Position)
+		}
+	case *ast.Boolean:
+		if exp.Operator == "!" {
+			return newBoolean(!right.Value, exp.Token.Position)
+		}
+	}
+
+	// we do not know what !5 means until the evaluator decides what is truthy, so leave it alone
+	return nil
+}
+
+func foldInfix(exp *ast.InfixExpression) ast.Expression {
+	switch left := exp.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := exp.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+		return foldIntegerInfix(exp.Operator, left.Value, right.Value, exp.Token.Position)
+	case *ast.Boolean:
+		right, ok := exp.Right.(*ast.Boolean)
+		if !ok {
+			return nil
+		}
+		return foldBooleanInfix(exp.Operator, left.Value, right.Value, exp.Token.Position)
+	}
+
+	return nil
+}
+
+// foldIntegerInfix leaves arithmetic that overflows an int64 alone. Go would wrap it around silently and the folded literal would not even parse again, the evaluator gets to decide what overflow means
+func foldIntegerInfix(operator string, left, right int64, position token.Position) ast.Expression {
+	switch operator {
+	case "+":
+		sum := left + right
+		// adding a positive number must make left bigger and a negative one smaller, anything else wrapped around
+		if (sum > left) != (right > 0) {
+			return nil
+		}
+		return foldedInteger(sum, position)
+	case "-":
+		difference := left - right
+		if (difference < left) != (right > 0) {
+			return nil
+		}
+		return foldedInteger(difference, position)
+	case "*":
+		if left == 0 || right == 0 {
+			return foldedInteger(0, position)
+		}
+		product := left * right
+		// dividing the product by one operand gives back the other unless it wrapped around. the one case that check misses is the smallest int64 times -1, which wraps to itself
+		if product/right != left || left == math.MinInt64 && right == -1 {
+			return nil
+		}
+		return foldedInteger(product, position)
+	case "/":
+		// division by zero is a runtime error, keep the expression so it is reported when evaluated
+		if right == 0 {
+			return nil
+		}
+		// the smallest int64 divided by -1 is one more than the biggest int64
+		if left == math.MinInt64 && right == -1 {
+			return nil
+		}
+		return foldedInteger(left/right, position)
+	case "<":
+		return newBoolean(left < right, position)
+	case ">":
+		return newBoolean(left > right, position)
+	case "==":
+		return newBoolean(left == right, position)
+	case "!=":
+		return newBoolean(left != right, position)
+	}
+
+	return nil
+}
+
+func foldBooleanInfix(operator string, left, right bool, position token.Position) ast.Expression {
+	switch operator {
+	case "==":
+		return newBoolean(left == right, position)
+	case "!=":
+		return newBoolean(left != right, position)
+	}
+
+	return nil
+}
+
+func removeUnreachableCode(program *ast.RootNode) {
+	for i, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			program.Statements = program.Statements[:i+1]
+			return
+		}
+	}
+}
+
+// foldedInteger is newInteger for results of folding. The smallest int64 is left unfolded: its literal would be read back as - applied to a number too big for an int64, which does not parse
+func foldedInteger(value int64, position token.Position) ast.Expression {
+	if value == math.MinInt64 {
+		return nil
+	}
+	return newInteger(value, position)
+}
+
+// folded literals take the position of the expression they replace so errors and tooling still point at the right place in the source
+func newInteger(value int64, position token.Position) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10), Position: position},
+		Value: value,
+	}
+}
+
+func newBoolean(value bool, position token.Position) *ast.Boolean {
+	tok := token.Token{Type: token.FALSE, Literal: "false", Position: position}
+	if value {
+		tok = token.Token{Type: token.TRUE, Literal: "true", Position: position}
+	}
+
+	return &ast.Boolean{Token: tok, Value: value}
+}
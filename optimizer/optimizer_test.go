@@ -0,0 +1,170 @@
+package optimizer_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ekediala/interpreter/ast"
+	"github.com/ekediala/interpreter/lexer"
+	"github.com/ekediala/interpreter/optimizer"
+	"github.com/ekediala/interpreter/parser"
+	"github.com/ekediala/interpreter/token"
+)
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 * 3 + x", "(6 + x)"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"1 + 2 + 3", "6"},
+		{"-5", "-5"},
+		{"-(5 + 5)", "-10"},
+		{"10 / 3", "3"},
+		{"1 / 0", "(1 / 0)"},
+		{"1 < 2", "true"},
+		{"1 > 2 == false", "true"},
+		{"!true", "false"},
+		{"!(true == false)", "true"},
+		{"true != false", "true"},
+		{"!5", "(!5)"},
+		{"true + 1", "(true + 1)"},
+		{"a * b", "(a * b)"},
+		{"let x = 2 * 3;", "let x = 6;"},
+		{"let [a, b] = 1 + 1 == 2;", "let [a, b] = true;"},
+		{"return 10 / 2 - 1;", "return 4;"},
+		{"let y = match (x) { 1 => 2 * 3, n if n > 1 + 1 => -(1 + 2), _ => !true };", "let y = match (x) { 1 => 6, n if (n > 2) => -3, _ => false };"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		optimizer.Optimize(program, optimizer.ConstantFolding)
+
+		if program.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestConstantFoldingOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775806 + 1", "9223372036854775807"},
+		{"9223372036854775807 + 1", "(9223372036854775807 + 1)"},
+		{"-9223372036854775807 + -1", "(-9223372036854775807 + -1)"},
+		{"0 - 9223372036854775807", "-9223372036854775807"},
+		{"-9223372036854775807 - 2", "(-9223372036854775807 - 2)"},
+		{"9223372036854775807 - -1", "(9223372036854775807 - -1)"},
+		{"4611686018427387903 * 2", "9223372036854775806"},
+		{"4611686018427387904 * 2", "(4611686018427387904 * 2)"},
+		{"3037000500 * 3037000500", "(3037000500 * 3037000500)"},
+		{"-3037000500 * 3037000500", "(-3037000500 * 3037000500)"},
+		{"0 * 9223372036854775807", "0"},
+		// the smallest int64 does not overflow, but -9223372036854775808 would not parse again
+		{"-9223372036854775807 - 1", "(-9223372036854775807 - 1)"},
+		{"-4611686018427387904 * 2", "(-4611686018427387904 * 2)"},
+		{"-(-9223372036854775807 - 1)", "(-(-9223372036854775807 - 1))"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		optimizer.Optimize(program, optimizer.ConstantFolding)
+
+		if program.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+
+		// whatever folding produced has to parse again
+		parse(t, program.String())
+	}
+
+	minInt := &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "-9223372036854775808"}, Value: math.MinInt64}
+	minusOne := &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "-1"}, Value: -1}
+	for _, exp := range []ast.Expression{
+		&ast.PrefixExpression{Token: token.Token{Type: token.MINUS, Literal: "-"}, Operator: "-", Right: minInt},
+		&ast.InfixExpression{Token: token.Token{Type: token.ASTERISK, Literal: "*"}, Operator: "*", Left: minInt, Right: minusOne},
+		&ast.InfixExpression{Token: token.Token{Type: token.ASTERISK, Literal: "*"}, Operator: "*", Left: minusOne, Right: minInt},
+		&ast.InfixExpression{Token: token.Token{Type: token.SLASH, Literal: "/"}, Operator: "/", Left: minInt, Right: minusOne},
+	} {
+		program := &ast.RootNode{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: exp}}}
+		optimizer.Optimize(program, optimizer.ConstantFolding)
+
+		if folded := program.Statements[0].(*ast.ExpressionStatement).Expression; folded != exp {
+			t.Errorf("%s overflows and should not be folded; got %s", exp, folded)
+		}
+	}
+}
+
+func TestConstantFoldingProducesLiterals(t *testing.T) {
+	program := parse(t, "2 * 3 + 4; 1 == 1;")
+	optimizer.Optimize(program, optimizer.ConstantFolding)
+
+	integer, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("expression not *ast.IntegerLiteral; got %T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+
+	if integer.Value != 10 || integer.Token.Type != token.INT {
+		t.Errorf("integer wrong; got value %d and token %+v", integer.Value, integer.Token)
+	}
+
+	boolean, ok := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.Boolean)
+	if !ok {
+		t.Fatalf("expression not *ast.Boolean; got %T", program.Statements[1].(*ast.ExpressionStatement).Expression)
+	}
+
+	if !boolean.Value || boolean.Token.Type != token.TRUE {
+		t.Errorf("boolean wrong; got value %t and token %+v", boolean.Value, boolean.Token)
+	}
+}
+
+func TestUnreachableCode(t *testing.T) {
+	program := parse(t, "a; return 5; b; c;")
+	optimizer.Optimize(program, optimizer.UnreachableCode)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements; got %d", len(program.Statements))
+	}
+
+	if _, ok := program.Statements[1].(*ast.ReturnStatement); !ok {
+		t.Errorf("program.Statements[1] not *ast.ReturnStatement; got %T", program.Statements[1])
+	}
+}
+
+func TestOptimizeRunsEveryPassByDefault(t *testing.T) {
+	program := parse(t, "1 + 1; return 5; 2 + 2;")
+	optimizer.Optimize(program)
+
+	expected := "2return 5;"
+	if program.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, pass := range optimizer.Passes() {
+		found, ok := optimizer.Lookup(pass.Name)
+		if !ok || found.Name != pass.Name {
+			t.Errorf("Lookup(%q) did not find the pass", pass.Name)
+		}
+	}
+
+	if _, ok := optimizer.Lookup("does-not-exist"); ok {
+		t.Errorf("Lookup found a pass that does not exist")
+	}
+}
+
+func parse(t *testing.T, input string) *ast.RootNode {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"fmt"
-	"log/slog"
 	"strconv"
 
 	"github.com/ekediala/interpreter/ast"
@@ -41,18 +40,38 @@ type Parser struct {
 	lexer        *lexer.Lexer
 	currentToken token.Token
 	nextToken    token.Token
-	errors       []string
+	errors       []ParseError
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	depth    int // how many expressions we are currently nested in
+	maxDepth int
+	braces   int // how many { we moved past without their }
+
+	tracer tracer
+}
+
+// DefaultMaxDepth is how deeply expressions may nest before the parser gives up on them, unless WithMaxDepth says otherwise
+const DefaultMaxDepth = 1000
+
+// Option configures a Parser. Options are applied by New in the order they are given
+type Option func(*Parser)
+
+// WithMaxDepth limits how deeply expressions may nest. Each expression is parsed by a recursive call, so without a limit something like a few million "-" in a row overflows the Go stack and takes the whole process down with it
+func WithMaxDepth(n int) Option {
+	return func(p *Parser) {
+		p.maxDepth = n
+	}
 }
 
-func New(lexer *lexer.Lexer) *Parser {
+func New(lexer *lexer.Lexer, opts ...Option) *Parser {
 	p := Parser{
 		lexer:          lexer,
-		errors:         make([]string, 0, 20),
+		errors:         make([]ParseError, 0, 20),
 		prefixParseFns: map[token.TokenType]prefixParseFn{},
 		infixParseFns:  map[token.TokenType]infixParseFn{},
+		maxDepth:       DefaultMaxDepth,
 	}
 
 	p.registerPrefixFn(token.IDENTIFIER, p.parseIdentifier)
@@ -62,6 +81,7 @@ func New(lexer *lexer.Lexer) *Parser {
 	p.registerPrefixFn(token.TRUE, p.parseBoolean)
 	p.registerPrefixFn(token.FALSE, p.parseBoolean)
 	p.registerPrefixFn(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefixFn(token.MATCH, p.parseMatchExpression)
 
 	p.registerInfixFn(token.PLUS, p.parseInfixExpression)
 	p.registerInfixFn(token.MINUS, p.parseInfixExpression)
@@ -72,24 +92,59 @@ func New(lexer *lexer.Lexer) *Parser {
 	p.registerInfixFn(token.LT, p.parseInfixExpression)
 	p.registerInfixFn(token.GT, p.parseInfixExpression)
 
+	for _, opt := range opts {
+		opt(&p)
+	}
+
 	// read tokens twice so that currentToken and nextToken are set correctly
 	p.next()
 	p.next()
 	return &p
 }
 
+// ParseError is a problem the parser found at a position in the source
+type ParseError struct {
+	Position token.Position
+	Message  string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Message)
+}
+
+// Errors returns the message of every error found while parsing
 func (p *Parser) Errors() []string {
+	messages := make([]string, 0, len(p.errors))
+	for _, err := range p.errors {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
+
+// ParseErrors returns every error found while parsing along with where in the source it was found
+func (p *Parser) ParseErrors() []ParseError {
 	return p.errors
 }
 
+func (p *Parser) addError(position token.Position, msg string) {
+	p.errors = append(p.errors, ParseError{Position: position, Message: msg})
+}
+
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %q, got %s instead", t, p.nextToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.nextToken.Position, msg)
 }
 
 func (p *Parser) next() {
 	p.currentToken = p.nextToken
 	p.nextToken = p.lexer.ReadAndAdvanceToken()
+
+	switch p.currentToken.Type {
+	case token.LBRACE:
+		p.braces++
+	case token.RBRACE:
+		p.braces--
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.RootNode {
@@ -103,17 +158,33 @@ func (p *Parser) ParseProgram() *ast.RootNode {
 		}
 		p.next()
 	}
+
+	// the lexer hands us EOF when it cannot read any further, make sure that does not pass for the end of the source
+	if err := p.lexer.Err(); err != nil {
+		p.addError(p.currentToken.Position, fmt.Sprintf("could not read source: %s", err))
+	}
+
 	return &program
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currentToken.Type {
+	// parseLetStatement returns a nil *ast.LetStatement when it fails. returned as is, that is a non-nil ast.Statement holding a nil pointer which ParseProgram cannot tell apart from a statement
 	case token.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 
 	case token.RETURN:
 		return p.parseReturnStatement()
 
+	case token.STRUCT:
+		if stmt := p.parseStructStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -121,9 +192,17 @@ func (p *Parser) parseStatement() ast.Statement {
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := ast.ReturnStatement{Token: p.currentToken}
+
+	// a bare return; has no value
+	if p.nextTokenIs(token.SEMICOLON) || p.nextTokenIs(token.EOF) {
+		p.next()
+		return &stmt
+	}
+
 	p.next()
+	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.currentTokenIs(token.SEMICOLON) {
+	if p.nextTokenIs(token.SEMICOLON) {
 		p.next()
 	}
 
@@ -135,16 +214,16 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Token: p.currentToken,
 	}
 
-	// let statements must be followed by an identifier
-	if !p.expectPeek(token.IDENTIFIER) {
+	// let statements must be followed by an identifier, or a [ or { that starts a pattern destructuring the value
+	if !p.nextTokenIs(token.LBRACKET) && !p.nextTokenIs(token.LBRACE) && !p.expectPeek(token.IDENTIFIER) {
 		return nil
 	}
 
-	// advance tokens to parse the identifier
+	// advance tokens to parse the identifier or pattern
 	p.next()
-	stmt.Name = &ast.Identifier{
-		Token: p.currentToken,
-		Value: p.currentToken.Literal,
+	stmt.Name = p.parsePattern()
+	if stmt.Name == nil {
+		return nil
 	}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -156,16 +235,182 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	// we do not need to parse the assignment operator, so advance tokens again
 	p.next()
 
-	// skip parsing expressions
-	for !p.currentTokenIs(token.SEMICOLON) {
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.nextTokenIs(token.SEMICOLON) {
+		p.next()
+	}
+
+	return &stmt
+}
+
+// parsePattern parses what a let statement binds to, starting at the current token. It returns nil when the pattern is malformed
+func (p *Parser) parsePattern() ast.Pattern {
+	defer p.untrace(p.trace("parsePattern"))
+
+	var pattern ast.Pattern
+	switch p.currentToken.Type {
+	case token.IDENTIFIER:
+		return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	case token.LBRACKET:
+		pattern = p.parseArrayPattern()
+	case token.LBRACE:
+		pattern = p.parseHashPattern()
+	}
+
+	// nil pointers returned as a Pattern would not compare equal to nil, see parseStatement
+	if pattern == nil {
+		return nil
+	}
+
+	// a name bound twice in the same pattern is most likely a typo, and there would be no telling which value it ends up with
+	seen := map[string]bool{}
+	for _, ident := range ast.BoundIdentifiers(pattern) {
+		if seen[ident.Value] {
+			p.addError(ident.Token.Position, fmt.Sprintf("%s is bound more than once in %s", ident.Value, pattern))
+			return nil
+		}
+		seen[ident.Value] = true
+	}
+
+	return pattern
+}
+
+// parseArrayPattern parses [a, b, ...rest]. The rest element is optional and must come last
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := ast.ArrayPattern{Token: p.currentToken, Elements: []*ast.Identifier{}}
+
+	for !p.nextTokenIs(token.RBRACKET) {
+		if p.nextTokenIs(token.ELLIPSIS) {
+			p.next()
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			p.next()
+			pattern.Rest = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+			if !p.nextTokenIs(token.RBRACKET) {
+				p.addError(p.nextToken.Position, fmt.Sprintf("%s%s must be the last element of an array pattern", token.ELLIPSIS, pattern.Rest))
+				return nil
+			}
+			break
+		}
+
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		p.next()
+		pattern.Elements = append(pattern.Elements, &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal})
+
+		if !p.nextTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		if p.nextTokenIs(token.COMMA) {
+			p.next()
+		}
+	}
+
+	// move onto the ]
+	p.next()
+
+	return &pattern
+}
+
+// parseHashPattern parses {name, age: years}. name on its own binds the value under the key name to name
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := ast.HashPattern{Token: p.currentToken, Pairs: []*ast.HashPatternPair{}}
+
+	for !p.nextTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
 		p.next()
+
+		key := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		pair := ast.HashPatternPair{Key: key, Value: key}
+
+		if p.nextTokenIs(token.COLON) {
+			p.next()
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			p.next()
+			pair.Value = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		}
+		pattern.Pairs = append(pattern.Pairs, &pair)
+
+		if !p.nextTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		if p.nextTokenIs(token.COMMA) {
+			p.next()
+		}
 	}
 
+	// move onto the }
+	p.next()
+
+	return &pattern
+}
+
+// parseStructStatement parses struct Name {field, ...}, starting at the struct keyword. It returns nil when the declaration is malformed
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	defer p.untrace(p.trace("parseStructStatement"))
+
+	stmt := ast.StructStatement{Token: p.currentToken, Fields: []*ast.Identifier{}}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	p.next()
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.next()
+	braces := p.braces
+
+	ok := p.parseStructFields(&stmt)
+	if ok {
+		// move onto the }
+		p.next()
+	} else {
+		p.skipToClosingBrace(braces)
+	}
+
+	if p.nextTokenIs(token.SEMICOLON) {
+		p.next()
+	}
+
+	if !ok {
+		return nil
+	}
 	return &stmt
 }
 
+// parseStructFields parses the field names of stmt up to, but not including, the closing }. It reports whether they were well formed
+func (p *Parser) parseStructFields(stmt *ast.StructStatement) bool {
+	for !p.nextTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return false
+		}
+		p.next()
+		stmt.Fields = append(stmt.Fields, &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal})
+
+		if !p.nextTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return false
+		}
+		if p.nextTokenIs(token.COMMA) {
+			p.next()
+		}
+	}
+
+	return true
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	defer untrace(trace("parseExpressionStatement"))
+	defer p.untrace(p.trace("parseExpressionStatement"))
 
 	stmt := ast.ExpressionStatement{Token: p.currentToken}
 
@@ -179,7 +424,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
-	defer untrace(trace("parsePrefixExpression"))
+	defer p.untrace(p.trace("parsePrefixExpression"))
 
 	prefixExp := ast.PrefixExpression{
 		Operator: p.currentToken.Literal,
@@ -193,7 +438,19 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
-	defer untrace(trace("parseExpression"))
+	defer p.untrace(p.trace("parseExpression"))
+
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > p.maxDepth {
+		p.addError(p.currentToken.Position, fmt.Sprintf("expression is nested more than %d levels deep", p.maxDepth))
+		// every enclosing expression would go on to parse whatever follows and run into the limit again, skip the rest of the statement so they all unwind right away
+		for !p.nextTokenIs(token.SEMICOLON) && !p.nextTokenIs(token.EOF) {
+			p.next()
+		}
+		return nil
+	}
 
 	prefix := p.prefixParseFns[p.currentToken.Type]
 	if prefix == nil {
@@ -236,16 +493,67 @@ func (p *Parser) nextTokenIs(t token.TokenType) bool {
 
 func (p *Parser) parseIdentifier() ast.Expression {
 	exp := ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	// a name followed by { builds a struct, like Point{x: 1, y: 2}
+	if p.nextTokenIs(token.LBRACE) {
+		return p.parseStructLiteral(&exp)
+	}
+
 	return &exp
 }
 
+// parseStructLiteral parses the {field: value, ...} that follows name, with name as the current token. It returns nil when the literal is malformed
+func (p *Parser) parseStructLiteral(name *ast.Identifier) ast.Expression {
+	defer p.untrace(p.trace("parseStructLiteral"))
+
+	p.next()
+	literal := ast.StructLiteral{Token: p.currentToken, Name: name, Fields: []*ast.StructField{}}
+	braces := p.braces
+
+	for !p.nextTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENTIFIER) {
+			p.skipToClosingBrace(braces)
+			return nil
+		}
+		p.next()
+		field := ast.StructField{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}}
+
+		if !p.expectPeek(token.COLON) {
+			p.skipToClosingBrace(braces)
+			return nil
+		}
+		p.next()
+		p.next()
+
+		field.Value = p.parseExpression(LOWEST)
+		if field.Value == nil {
+			p.skipToClosingBrace(braces)
+			return nil
+		}
+		literal.Fields = append(literal.Fields, &field)
+
+		if !p.nextTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			p.skipToClosingBrace(braces)
+			return nil
+		}
+		if p.nextTokenIs(token.COMMA) {
+			p.next()
+		}
+	}
+
+	// move onto the }
+	p.next()
+
+	return &literal
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-	defer untrace(trace("parseIntegerLiteral"))
+	defer p.untrace(p.trace("parseIntegerLiteral"))
 
 	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("%q could not be parsed into int64", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.currentToken.Position, msg)
 		return nil
 	}
 
@@ -258,7 +566,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
-	defer untrace(trace("parseInfixExpression"))
+	defer p.untrace(p.trace("parseInfixExpression"))
 
 	exp := ast.InfixExpression{
 		Token:    p.currentToken,
@@ -279,12 +587,15 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
-	slog.Info("parse grouped exp", "curr token", p.currentToken)
+	defer p.untrace(p.trace("parseGroupedExpression"))
+
 	p.next()
 
 	exp := p.parseExpression(LOWEST)
-
-	slog.Info("parse grouped exp", "exp", exp, "expect peek", p.nextToken)
+	// the inner expression already reported why it failed, a missing ")" on top of that is noise
+	if exp == nil {
+		return nil
+	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -295,6 +606,231 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return exp
 }
 
+// parseMatchExpression parses match (value) { pattern => body, pattern if guard => body, ... }. A comma after the last arm is allowed
+func (p *Parser) parseMatchExpression() ast.Expression {
+	defer p.untrace(p.trace("parseMatchExpression"))
+
+	exp := ast.MatchExpression{Token: p.currentToken, Arms: []*ast.MatchArm{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.next()
+	p.next()
+
+	exp.Value = p.parseExpression(LOWEST)
+	if exp.Value == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	p.next()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.next()
+	braces := p.braces
+
+	reachable := newArmReachability()
+	for !p.nextTokenIs(token.RBRACE) {
+		p.next()
+
+		position := p.currentToken.Position
+		arm := p.parseMatchArm()
+		if arm == nil {
+			p.skipToClosingBrace(braces)
+			return nil
+		}
+		if msg, ok := reachable.check(arm); !ok {
+			p.addError(position, msg)
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if !p.nextTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			p.skipToClosingBrace(braces)
+			return nil
+		}
+		if p.nextTokenIs(token.COMMA) {
+			p.next()
+		}
+	}
+
+	// move onto the }
+	p.next()
+
+	return &exp
+}
+
+// skipToClosingBrace moves onto the } that closes a match expression or a struct once something inside failed to parse, so what is left over is not read as statements of its own.
+// braces is how many { were open right after the { that is to be closed
+func (p *Parser) skipToClosingBrace(braces int) {
+	for !p.currentTokenIs(token.EOF) && !(p.currentTokenIs(token.RBRACE) && p.braces < braces) {
+		p.next()
+	}
+}
+
+// parseMatchArm parses pattern => body, with an optional if guard between the two, starting at the first token of the pattern
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	defer p.untrace(p.trace("parseMatchArm"))
+
+	arm := ast.MatchArm{}
+
+	switch p.currentToken.Type {
+	case token.INT:
+		integer, ok := p.parseIntegerLiteral().(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+		arm.Pattern = integer
+	case token.MINUS:
+		integer := p.parseNegativeIntegerPattern()
+		if integer == nil {
+			return nil
+		}
+		arm.Pattern = integer
+	case token.TRUE, token.FALSE:
+		arm.Pattern = &ast.Boolean{Token: p.currentToken, Value: p.currentTokenIs(token.TRUE)}
+	case token.IDENTIFIER, token.LBRACKET, token.LBRACE:
+		if p.currentToken.Literal == ast.Discard {
+			arm.Pattern = &ast.Wildcard{Token: p.currentToken}
+			break
+		}
+		if arm.Pattern = p.parsePattern(); arm.Pattern == nil {
+			return nil
+		}
+	default:
+		p.addError(p.currentToken.Position, fmt.Sprintf("expected a pattern, got %s instead", p.currentToken.Type))
+		return nil
+	}
+
+	if p.nextTokenIs(token.IF) {
+		p.next()
+		p.next()
+		if arm.Guard = p.parseExpression(LOWEST); arm.Guard == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.next()
+	p.next()
+
+	if arm.Body = p.parseExpression(LOWEST); arm.Body == nil {
+		return nil
+	}
+
+	return &arm
+}
+
+// parseNegativeIntegerPattern parses the -1 of a match arm into a single literal, the way the arm sees it, rather than - applied to 1
+func (p *Parser) parseNegativeIntegerPattern() *ast.IntegerLiteral {
+	minus := p.currentToken
+	if !p.expectPeek(token.INT) {
+		return nil
+	}
+	p.next()
+
+	literal := minus.Literal + p.currentToken.Literal
+	value, err := strconv.ParseInt(literal, 0, 64)
+	if err != nil {
+		p.addError(minus.Position, fmt.Sprintf("%q could not be parsed into int64", literal))
+		return nil
+	}
+
+	return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal, Position: minus.Position}, Value: value}
+}
+
+// armReachability remembers what the arms of a match expression matched so far, to tell when an arm can never be picked
+type armReachability struct {
+	catchAll ast.Pattern  // an earlier arm without a guard that matches every value
+	literals map[any]bool // literal values an earlier arm without a guard matches
+	arrays   []*ast.ArrayPattern
+	hashes   []*ast.HashPattern
+}
+
+func newArmReachability() *armReachability {
+	return &armReachability{literals: map[any]bool{}}
+}
+
+// check reports why arm can never be picked when an earlier arm already matches every value it does. Guarded arms may not match, so they never make a later arm unreachable
+func (r *armReachability) check(arm *ast.MatchArm) (string, bool) {
+	if r.catchAll != nil {
+		return fmt.Sprintf("unreachable match arm, the earlier arm %s matches every value", r.catchAll), false
+	}
+
+	var literal any
+	switch pattern := arm.Pattern.(type) {
+	case *ast.Identifier, *ast.Wildcard:
+		if arm.Guard == nil {
+			r.catchAll = pattern
+		}
+		return "", true
+	case *ast.IntegerLiteral:
+		literal = pattern.Value
+	case *ast.Boolean:
+		literal = pattern.Value
+	case *ast.ArrayPattern:
+		for _, earlier := range r.arrays {
+			if arrayCovers(earlier, pattern) {
+				return fmt.Sprintf("unreachable match arm, the earlier arm %s matches every array %s does", earlier, pattern), false
+			}
+		}
+		if arm.Guard == nil {
+			r.arrays = append(r.arrays, pattern)
+		}
+		return "", true
+	case *ast.HashPattern:
+		for _, earlier := range r.hashes {
+			if hashCovers(earlier, pattern) {
+				return fmt.Sprintf("unreachable match arm, the earlier arm %s matches every hash %s does", earlier, pattern), false
+			}
+		}
+		if arm.Guard == nil {
+			r.hashes = append(r.hashes, pattern)
+		}
+		return "", true
+	default:
+		return "", true
+	}
+
+	if r.literals[literal] {
+		return fmt.Sprintf("unreachable match arm, an earlier arm already matches %s", arm.Pattern), false
+	}
+	if arm.Guard == nil {
+		r.literals[literal] = true
+	}
+	return "", true
+}
+
+// arrayCovers reports whether every array later matches is matched by earlier too. The elements of an array pattern are all identifiers, which match anything, so only the lengths matter:
+// without a rest element a pattern matches arrays of exactly its length, with one it matches arrays at least that long
+func arrayCovers(earlier, later *ast.ArrayPattern) bool {
+	if earlier.Rest != nil {
+		return len(later.Elements) >= len(earlier.Elements)
+	}
+	return later.Rest == nil && len(later.Elements) == len(earlier.Elements)
+}
+
+// hashCovers reports whether every hash later matches is matched by earlier too. A hash pattern matches hashes that have all of its keys, so a pattern asking for every key earlier asks for is covered by it
+func hashCovers(earlier, later *ast.HashPattern) bool {
+	keys := map[string]bool{}
+	for _, pair := range later.Pairs {
+		keys[pair.Key.Value] = true
+	}
+
+	for _, pair := range earlier.Pairs {
+		if !keys[pair.Key.Value] {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Parser) registerPrefixFn(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -305,7 +841,7 @@ func (p *Parser) registerInfixFn(tokenType token.TokenType, fn infixParseFn) {
 
 func (p *Parser) addNoPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.currentToken.Position, msg)
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -1,9 +1,13 @@
 package parser_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/ekediala/interpreter/ast"
 	"github.com/ekediala/interpreter/lexer"
@@ -49,6 +53,89 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedName  string
+		expectedBound []string
+	}{
+		{"let [a, b] = arr;", "[a, b]", []string{"a", "b"}},
+		{"let [a, b, ...rest] = arr;", "[a, b, ...rest]", []string{"a", "b", "rest"}},
+		{"let [...all] = arr;", "[...all]", []string{"all"}},
+		{"let [first,] = arr;", "[first]", []string{"first"}},
+		{"let [] = arr;", "[]", []string{}},
+		{"let {name, age: years} = person;", "{name, age: years}", []string{"name", "years"}},
+		{"let {name: name} = person;", "{name}", []string{"name"}},
+		{"let {} = person;", "{}", []string{}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement; got %d", tt.input, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("%q: program.Statements[0] not *ast.LetStatement; got %T", tt.input, program.Statements[0])
+		}
+
+		if stmt.Name.String() != tt.expectedName {
+			t.Errorf("%q: stmt.Name.String() not %q; got %q", tt.input, tt.expectedName, stmt.Name.String())
+		}
+
+		var bound []string
+		for _, ident := range ast.BoundIdentifiers(stmt.Name) {
+			bound = append(bound, ident.Value)
+		}
+		if strings.Join(bound, ",") != strings.Join(tt.expectedBound, ",") {
+			t.Errorf("%q: bound identifiers not %q; got %q", tt.input, tt.expectedBound, bound)
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a, ...rest, b] = arr;", "1:16: ...rest must be the last element of an array pattern"},
+		{"let [a b] = arr;", `1:8: expected next token to be ",", got IDENTIFIER instead`},
+		{"let [a, 5] = arr;", `1:9: expected next token to be "IDENTIFIER", got INT instead`},
+		{"let [...] = arr;", `1:9: expected next token to be "IDENTIFIER", got ] instead`},
+		{"let [a, a] = arr;", "1:9: a is bound more than once in [a, a]"},
+		{"let {name: } = person;", `1:12: expected next token to be "IDENTIFIER", got } instead`},
+		{"let {name, age: name} = person;", "1:17: name is bound more than once in {name, age: name}"},
+		{"let {a b} = person;", `1:8: expected next token to be ",", got IDENTIFIER instead`},
+		{"let [a] person;", `1:9: expected next token to be "=", got IDENTIFIER instead`},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expectedError {
+			t.Errorf("%q: error wrong; expected %q, got %q", tt.input, tt.expectedError, errors[0].Error())
+		}
+
+		for _, stmt := range program.Statements {
+			if _, ok := stmt.(*ast.LetStatement); ok {
+				t.Errorf("%q: a let statement that failed to parse ended up in the program", tt.input)
+			}
+		}
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	input := `
 		return 5;
@@ -80,6 +167,39 @@ func TestReturnStatement(t *testing.T) {
 	}
 }
 
+func TestLetAndReturnValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1 + 2 * 3;", "let x = (1 + (2 * 3));"},
+		{"let [a, ...b] = -c", "let [a, ...b] = (-c);"},
+		{"let y = match (x) { 1 => 2, _ => 3 };", "let y = match (x) { 1 => 2, _ => 3 };"},
+		{"return a == b;", "return (a == b);"},
+		{"return;", "return ;"},
+		{"return", "return ;"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: program.String() not %q; got %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	// the value of a let is checked like any other expression
+	p := parser.New(lexer.New("let y = match (x) { _ => 1, 2 => 3 };"))
+	p.ParseProgram()
+
+	expected := "1:29: unreachable match arm, the earlier arm _ matches every value"
+	if errors := p.ParseErrors(); len(errors) != 1 || errors[0].Error() != expected {
+		t.Errorf("expected the error %q; got %v", expected, errors)
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 
 	variableName := "foobar"
@@ -386,6 +506,357 @@ func TestBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestSourceReadError(t *testing.T) {
+	source := io.MultiReader(strings.NewReader("5 + 5;"), iotest.ErrReader(errors.New("connection reset")))
+
+	p := parser.New(lexer.NewReader(source))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 parser error; got %d: %q", len(errors), errors)
+	}
+
+	expected := "could not read source: connection reset"
+	if errors[0] != expected {
+		t.Errorf("error wrong; expected %q; got %q", expected, errors[0])
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	input := `let x 5;
+let = 10;
+  1 + ;`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	expected := []parser.ParseError{
+		{Position: token.Position{Line: 1, Column: 7}, Message: `expected next token to be "=", got INT instead`},
+		{Position: token.Position{Line: 2, Column: 5}, Message: `expected next token to be "IDENTIFIER", got = instead`},
+		{Position: token.Position{Line: 2, Column: 5}, Message: "no prefix parse function for = found"},
+		{Position: token.Position{Line: 3, Column: 7}, Message: "no prefix parse function for ; found"},
+	}
+
+	errors := p.ParseErrors()
+	if len(errors) != len(expected) {
+		t.Fatalf("expected %d errors; got %d: %v", len(expected), len(errors), errors)
+	}
+
+	for i, e := range expected {
+		if errors[i] != e {
+			t.Errorf("errors[%d] wrong; expected %v; got %v", i, e, errors[i])
+		}
+	}
+
+	// a let statement that failed to parse must not end up in the program as a nil statement
+	for i, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let == nil {
+			t.Errorf("program.Statements[%d] is a nil *ast.LetStatement", i)
+		}
+	}
+}
+
+func TestStatementsWithoutSemicolonAtEOF(t *testing.T) {
+	for _, input := range []string{"let x = 5", "return 5", "let y = 1 + 2"} {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Errorf("%q: program.Statements does not contain 1 statement; got %d", input, len(program.Statements))
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) { 0 => a, -1 => d, true => b, n if n > 10 => n * 2, [_, second, ..._] => second, [first, ...rest] => first, {name, age: _} => name, _ => c, }`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement; got %d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement; got %T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.MatchExpression; got %T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Value, "x") {
+		return
+	}
+
+	tests := []struct {
+		pattern string
+		guard   string
+		body    string
+	}{
+		{"0", "", "a"},
+		{"-1", "", "d"},
+		{"true", "", "b"},
+		{"n", "(n > 10)", "(n * 2)"},
+		{"[_, second, ..._]", "", "second"},
+		{"[first, ...rest]", "", "first"},
+		{"{name, age: _}", "", "name"},
+		{"_", "", "c"},
+	}
+
+	if len(exp.Arms) != len(tests) {
+		t.Fatalf("exp.Arms does not contain %d arms; got %d", len(tests), len(exp.Arms))
+	}
+
+	for i, tt := range tests {
+		arm := exp.Arms[i]
+
+		if arm.Pattern.String() != tt.pattern {
+			t.Errorf("arm %d: pattern not %q; got %q", i, tt.pattern, arm.Pattern.String())
+		}
+
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
+		if guard != tt.guard {
+			t.Errorf("arm %d: guard not %q; got %q", i, tt.guard, guard)
+		}
+
+		if arm.Body.String() != tt.body {
+			t.Errorf("arm %d: body not %q; got %q", i, tt.body, arm.Body.String())
+		}
+	}
+
+	if integer, ok := exp.Arms[1].Pattern.(*ast.IntegerLiteral); !ok || integer.Value != -1 {
+		t.Errorf("the pattern of the second arm is not the integer -1; got %#v", exp.Arms[1].Pattern)
+	}
+
+	if _, ok := exp.Arms[7].Pattern.(*ast.Wildcard); !ok {
+		t.Errorf("the pattern of the last arm is not *ast.Wildcard; got %T", exp.Arms[7].Pattern)
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{"match (x) { _ => 1, 2 => 3 }", []string{"1:21: unreachable match arm, the earlier arm _ matches every value"}},
+		{"match (x) { n => 1, 2 => 3, _ => 4 }", []string{
+			"1:21: unreachable match arm, the earlier arm n matches every value",
+			"1:29: unreachable match arm, the earlier arm n matches every value",
+		}},
+		{"match (x) { 1 => a, true => b, 1 => c }", []string{"1:32: unreachable match arm, an earlier arm already matches 1"}},
+		{"match (x) { false => a, false if y => b }", []string{"1:25: unreachable match arm, an earlier arm already matches false"}},
+		{"match (x) { -1 => a, 2 => b, -1 => c }", []string{"1:30: unreachable match arm, an earlier arm already matches -1"}},
+		// the arms after one that fails to parse are skipped, not read as statements
+		{"match (x) { + => 1, 2 => 3 }; y", []string{"1:13: expected a pattern, got + instead"}},
+		{"match (x) { - => 1 }", []string{`1:15: expected next token to be "INT", got => instead`}},
+		{"match (x) { -9223372036854775809 => 1 }", []string{`1:13: "-9223372036854775809" could not be parsed into int64`}},
+		{"match (x) 1;", []string{`1:11: expected next token to be "{", got INT instead`}},
+		{"match (x) { 1 => 1 2 => 2 }", []string{`1:20: expected next token to be ",", got INT instead`}},
+		{"match (x) { {a: 1} => 1, {b} => b }", []string{`1:17: expected next token to be "IDENTIFIER", got INT instead`}},
+		{"match (x) { 1 => match (y) { + => 1 }, 2 => 3 }", []string{"1:30: expected a pattern, got + instead"}},
+		{"match (x) { [a, a] => a }", []string{"1:17: a is bound more than once in [a, a]"}},
+		{"match (x) { [a] => 1, [b] => 2 }", []string{"1:23: unreachable match arm, the earlier arm [a] matches every array [b] does"}},
+		{"match (x) { [...r] => 1, [a] => 2 }", []string{"1:26: unreachable match arm, the earlier arm [...r] matches every array [a] does"}},
+		{"match (x) { [a, ...r] => 1, [_, b, ...c] => 2 }", []string{"1:29: unreachable match arm, the earlier arm [a, ...r] matches every array [_, b, ...c] does"}},
+		{"match (x) { {name} => 1, {age, name: n} => 2 }", []string{"1:26: unreachable match arm, the earlier arm {name} matches every hash {age, name: n} does"}},
+		{"match (x) { {} => 1, {name} => 2 }", []string{"1:22: unreachable match arm, the earlier arm {} matches every hash {name} does"}},
+		{"match (x) { 1 => }", []string{"1:18: no prefix parse function for } found"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("%q: expected %d errors; got %d: %v", tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i].Error() != expected {
+				t.Errorf("%q: errors[%d] wrong; expected %q, got %q", tt.input, i, expected, errors[i].Error())
+			}
+		}
+	}
+}
+
+func TestGuardedArmsStayReachable(t *testing.T) {
+	for _, input := range []string{
+		"match (x) { n if n > 1 => a, 1 if y => b, 1 => c, _ => d }",
+		"match (x) { true => a, false => b, _ => c }",
+		"match (x) { [a] if a > 1 => a, [b] => b, [b, c] => c, [b, ...c] => b, [...all] => all, _ => c }",
+		"match (x) { {name} if name => name, {name} => name, {age} => age, {} => 0 }",
+	} {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+		checkParserErrors(t, p)
+	}
+}
+
+func TestNestingDepthLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		opts       []parser.Option
+		expected   []parser.ParseError
+		statements int
+	}{
+		{"5,000,000 prefix operators", strings.Repeat("-", 5_000_000) + "1", nil, []parser.ParseError{
+			{Position: token.Position{Line: 1, Column: parser.DefaultMaxDepth + 1}, Message: "expression is nested more than 1000 levels deep"},
+		}, 1},
+		{"5,000,000 parentheses", strings.Repeat("(", 5_000_000) + "1" + strings.Repeat(")", 5_000_000), nil, []parser.ParseError{
+			{Position: token.Position{Line: 1, Column: parser.DefaultMaxDepth + 1}, Message: "expression is nested more than 1000 levels deep"},
+		}, 1},
+		{"custom limit", "--1; !!!true; 2;", []parser.Option{parser.WithMaxDepth(3)}, []parser.ParseError{
+			{Position: token.Position{Line: 1, Column: 9}, Message: "expression is nested more than 3 levels deep"},
+		}, 3},
+		{"within custom limit", "1 + (2); let x = !(5);", []parser.Option{parser.WithMaxDepth(3)}, nil, 2},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input), tt.opts...)
+		program := p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%s: expected %d errors; got %d: %v", tt.name, len(tt.expected), len(errors), errors)
+			continue
+		}
+
+		for i, e := range tt.expected {
+			if errors[i] != e {
+				t.Errorf("%s: errors[%d] wrong. expected %v; got %v", tt.name, i, e, errors[i])
+			}
+		}
+
+		// the statements after the one that went too deep still get parsed
+		if len(program.Statements) != tt.statements {
+			t.Errorf("%s: program.Statements does not contain %d statements; got %d", tt.name, tt.statements, len(program.Statements))
+		}
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string
+		fields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Point { x, y, };", "Point", []string{"x", "y"}},
+		{"struct Empty {}", "Empty", []string{}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement; got %d", tt.input, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("%q: program.Statements[0] is not *ast.StructStatement; got %T", tt.input, program.Statements[0])
+		}
+
+		if stmt.Name.Value != tt.name {
+			t.Errorf("%q: stmt.Name not %q; got %q", tt.input, tt.name, stmt.Name.Value)
+		}
+
+		fields := make([]string, 0, len(stmt.Fields))
+		for _, field := range stmt.Fields {
+			fields = append(fields, field.Value)
+		}
+		if !slices.Equal(fields, tt.fields) {
+			t.Errorf("%q: fields not %v; got %v", tt.input, tt.fields, fields)
+		}
+	}
+}
+
+func TestStructLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Point{x: 1, y: 2}", "Point{x: 1, y: 2}"},
+		{"Point{x: a + 1, y: -b,}", "Point{x: (a + 1), y: (-b)}"},
+		{"Empty{}", "Empty{}"},
+		{"let p = Line{from: Point{x: 0, y: 0}, to: q};", "let p = Line{from: Point{x: 0, y: 0}, to: q};"},
+		{"a + Point{x: 1} == b", "((a + Point{x: 1}) == b)"},
+		{"match (p) { {x} => Point{x: x, y: 0}, _ => p }", "match (p) { {x} => Point{x: x, y: 0}, _ => p }"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	p := parser.New(lexer.New("Point{x: 1}"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	literal, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.StructLiteral)
+	if !ok {
+		t.Fatalf("expression is not *ast.StructLiteral; got %T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+
+	if !testIdentifier(t, literal.Name, "Point") || len(literal.Fields) != 1 || !testIdentifier(t, literal.Fields[0].Name, "x") {
+		return
+	}
+	testIntegerLiteral(t, literal.Fields[0].Value, 1)
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{"struct 5;", []string{`1:8: expected next token to be "IDENTIFIER", got INT instead`}},
+		{"struct Point x", []string{`1:14: expected next token to be "{", got IDENTIFIER instead`}},
+		// the fields after one that fails to parse are skipped, not read as statements
+		{"struct Point { x, 1, y }; z", []string{`1:19: expected next token to be "IDENTIFIER", got INT instead`}},
+		{"struct Point { x y }", []string{`1:18: expected next token to be ",", got IDENTIFIER instead`}},
+		{"Point{x 1, y: 2}; z", []string{`1:9: expected next token to be ":", got INT instead`}},
+		{"Point{1: x}", []string{`1:7: expected next token to be "IDENTIFIER", got INT instead`}},
+		{"Point{x: }", []string{"1:10: no prefix parse function for } found"}},
+		{"Point{x: +, y: 2}", []string{"1:10: no prefix parse function for + found"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("%q: expected %d errors; got %d: %v", tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i].Error() != expected {
+				t.Errorf("%q: errors[%d] wrong; expected %q, got %q", tt.input, i, expected, errors[i].Error())
+			}
+		}
+	}
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	integer, ok := il.(*ast.IntegerLiteral)
 	if !ok {
@@ -420,8 +891,14 @@ func testLetStatement(t *testing.T, s ast.Statement, variableName string) bool {
 		return false
 	}
 
-	if letStmt.Name.Value != variableName {
-		t.Errorf("letStmt.Name.Value not %q; got: %q", variableName, letStmt.Name.Value)
+	name, ok := letStmt.Name.(*ast.Identifier)
+	if !ok {
+		t.Errorf("letStmt.Name not *ast.Identifier; got %T", letStmt.Name)
+		return false
+	}
+
+	if name.Value != variableName {
+		t.Errorf("letStmt.Name.Value not %q; got: %q", variableName, name.Value)
 		return false
 	}
 
@@ -1,32 +1,76 @@
 package parser
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
 )
 
-var traceLevel int = 0
-
 const traceIdentPlaceholder string = "\t"
 
-func identLevel() string {
-	return strings.Repeat(traceIdentPlaceholder, traceLevel-1)
+// tracer records every parse function the parser enters and leaves. Each parser gets its own so parsers running in different goroutines do not share the indentation level
+type tracer struct {
+	level  int
+	out    io.Writer    // BEGIN/END lines are written here when set
+	logger *slog.Logger // a record per BEGIN/END is logged here when set
+}
+
+// WithTrace writes an indented BEGIN/END line to w for every parse function the parser enters and leaves
+func WithTrace(w io.Writer) Option {
+	return func(p *Parser) {
+		p.tracer.out = w
+	}
 }
 
-func tracePrint(fs string) {
-	fmt.Printf("%s%s\n", identLevel(), fs)
+// WithTraceHandler hands h a record for every parse function the parser enters and leaves. The message is "begin" or "end" and the record carries the function, the depth of the call and the current token, which is enough to replay the Pratt parse steps
+func WithTraceHandler(h slog.Handler) Option {
+	return func(p *Parser) {
+		p.tracer.logger = slog.New(h)
+	}
 }
 
-func incIdent() { traceLevel = traceLevel + 1 }
-func decIdent() { traceLevel = traceLevel - 1 }
+func (t *tracer) enabled() bool {
+	return t.out != nil || t.logger != nil
+}
 
-func trace(msg string) string {
-	incIdent()
-	tracePrint("BEGIN " + msg)
-	return msg
+func (t *tracer) identLevel() string {
+	return strings.Repeat(traceIdentPlaceholder, t.level-1)
 }
 
-func untrace(msg string) {
-	tracePrint("END " + msg)
-	decIdent()
+func (p *Parser) tracePrint(event, fn string) {
+	if p.tracer.out != nil {
+		fmt.Fprintf(p.tracer.out, "%s%s %s\n", p.tracer.identLevel(), strings.ToUpper(event), fn)
+	}
+
+	if p.tracer.logger != nil {
+		p.tracer.logger.LogAttrs(context.Background(), slog.LevelInfo, event,
+			slog.String("fn", fn),
+			slog.Int("depth", p.tracer.level),
+			slog.String("token", string(p.currentToken.Type)),
+			slog.String("literal", p.currentToken.Literal),
+			slog.Int("line", p.currentToken.Position.Line),
+			slog.Int("column", p.currentToken.Position.Column),
+		)
+	}
+}
+
+func (p *Parser) trace(fn string) string {
+	if !p.tracer.enabled() {
+		return fn
+	}
+
+	p.tracer.level = p.tracer.level + 1
+	p.tracePrint("begin", fn)
+	return fn
+}
+
+func (p *Parser) untrace(fn string) {
+	if !p.tracer.enabled() {
+		return
+	}
+
+	p.tracePrint("end", fn)
+	p.tracer.level = p.tracer.level - 1
 }
@@ -0,0 +1,148 @@
+package parser_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ekediala/interpreter/lexer"
+	"github.com/ekediala/interpreter/parser"
+)
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+
+	p := parser.New(lexer.New("-a * 2"), parser.WithTrace(&out))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := strings.Join([]string{
+		"BEGIN parseExpressionStatement",
+		"\tBEGIN parseExpression",
+		"\t\tBEGIN parsePrefixExpression",
+		"\t\t\tBEGIN parseExpression",
+		"\t\t\tEND parseExpression",
+		"\t\tEND parsePrefixExpression",
+		"\t\tBEGIN parseInfixExpression",
+		"\t\t\tBEGIN parseExpression",
+		"\t\t\t\tBEGIN parseIntegerLiteral",
+		"\t\t\t\tEND parseIntegerLiteral",
+		"\t\t\tEND parseExpression",
+		"\t\tEND parseInfixExpression",
+		"\tEND parseExpression",
+		"END parseExpressionStatement",
+		"",
+	}, "\n")
+
+	if out.String() != expected {
+		t.Errorf("trace wrong;\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestTraceIsOffByDefault(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	p := parser.New(lexer.New("(1 + 2) * 3"))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	w.Close()
+	written, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 0 {
+		t.Errorf("parser without trace options wrote to stdout: %q", written)
+	}
+}
+
+func TestTraceHandler(t *testing.T) {
+	var out bytes.Buffer
+
+	p := parser.New(lexer.New("1 + x"), parser.WithTraceHandler(slog.NewJSONHandler(&out, nil)))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	type record struct {
+		Msg     string `json:"msg"`
+		Fn      string `json:"fn"`
+		Depth   int    `json:"depth"`
+		Token   string `json:"token"`
+		Literal string `json:"literal"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+	}
+
+	var records []record
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("trace line %q is not JSON: %s", line, err)
+		}
+		records = append(records, r)
+	}
+
+	if len(records) != 10 {
+		t.Fatalf("expected 10 trace records; got %d", len(records))
+	}
+
+	expected := []record{
+		{"begin", "parseExpressionStatement", 1, "INT", "1", 1, 1},
+		{"begin", "parseExpression", 2, "INT", "1", 1, 1},
+		{"begin", "parseIntegerLiteral", 3, "INT", "1", 1, 1},
+		{"end", "parseIntegerLiteral", 3, "INT", "1", 1, 1},
+		{"begin", "parseInfixExpression", 3, "+", "+", 1, 3},
+	}
+
+	for i, e := range expected {
+		if records[i] != e {
+			t.Errorf("record[%d] wrong; expected %+v, got %+v", i, e, records[i])
+		}
+	}
+}
+
+func TestTraceConcurrentParsers(t *testing.T) {
+	inputs := []string{"a + b * c + d / e - f", "!(true == true)", "(5 + 5) * 2 * (5 + 5)", "3 + 4; -5 * 5"}
+
+	// trace every input on its own first so there is something to compare the concurrent traces with
+	expected := make([]string, len(inputs))
+	for i, input := range inputs {
+		var out bytes.Buffer
+		parser.New(lexer.New(input), parser.WithTrace(&out)).ParseProgram()
+		expected[i] = out.String()
+	}
+
+	got := make([]string, len(inputs))
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				var out bytes.Buffer
+				parser.New(lexer.New(input), parser.WithTrace(&out)).ParseProgram()
+				got[i] = out.String()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range inputs {
+		if got[i] != expected[i] {
+			t.Errorf("trace for %q changed when parsed concurrently;\nexpected:\n%s\ngot:\n%s", inputs[i], expected[i], got[i])
+		}
+	}
+}
@@ -6,12 +6,33 @@ import (
 	"io"
 
 	"github.com/ekediala/interpreter/lexer"
-	"github.com/ekediala/interpreter/token"
+	"github.com/ekediala/interpreter/optimizer"
+	"github.com/ekediala/interpreter/parser"
 )
 
 const PROMPT = ">>"
 
-func Start(in io.Reader, out io.Writer) {
+type config struct {
+	dumpOptimized bool
+	passes        []optimizer.Pass
+}
+
+type Option func(*config)
+
+// WithOptimizedDump makes the REPL parse every line, run passes over it and print the optimized tree instead of the tokens. All passes are run when none is given
+func WithOptimizedDump(passes ...optimizer.Pass) Option {
+	return func(c *config) {
+		c.dumpOptimized = true
+		c.passes = passes
+	}
+}
+
+func Start(in io.Reader, out io.Writer, opts ...Option) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	scanner := bufio.NewScanner(in)
 
 	for {
@@ -24,8 +45,27 @@ func Start(in io.Reader, out io.Writer) {
 		line := scanner.Text()
 		l := lexer.New(line)
 
-		for tok := l.ReadAndAdvanceToken(); tok.Type != token.EOF; tok = l.ReadAndAdvanceToken() {
+		if cfg.dumpOptimized {
+			dumpOptimized(out, l, cfg.passes)
+			continue
+		}
+
+		for tok := range l.Tokens() {
 			fmt.Fprintf(out, "%+v\n", tok)
 		}
 	}
 }
+
+func dumpOptimized(out io.Writer, l *lexer.Lexer, passes []optimizer.Pass) {
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.ParseErrors()) != 0 {
+		for _, err := range p.ParseErrors() {
+			fmt.Fprintf(out, "\t%s\n", err)
+		}
+		return
+	}
+
+	fmt.Fprintln(out, optimizer.Optimize(program, passes...).String())
+}
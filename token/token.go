@@ -1,10 +1,23 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Position Position // where the first character of the token is in the source
+}
+
+// Position is a location in the source. Lines and columns start at 1 so they can be shown to users as is
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
@@ -31,21 +44,30 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	ARROW = "=>"
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	ELLIPSIS  = "..."
 
 	LPAREN = "("
 	RPAREN = ")"
 	LBRACE = "{"
 	RBRACE = "}"
 
+	LBRACKET = "["
+	RBRACKET = "]"
+
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MATCH    = "MATCH"
+	STRUCT   = "STRUCT"
 	TRUE     = "true"
 	FALSE    = "false"
 )
@@ -56,6 +78,8 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"match":  MATCH,
+	"struct": STRUCT,
 	"true":   TRUE,
 	"false":  FALSE,
 }